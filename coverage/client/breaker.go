@@ -0,0 +1,219 @@
+package client
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen - внешняя система считается недоступной, запрос даже не отправлялся
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState - состояние CircuitBreaker
+type BreakerState int
+
+const (
+	// BreakerClosed - запросы идут как обычно, сбои считаются
+	BreakerClosed BreakerState = iota
+	// BreakerOpen - сбоев было слишком много, запросы сразу получают ErrCircuitOpen
+	BreakerOpen
+	// BreakerHalfOpen - после CoolDown пропускается несколько пробных запросов
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerSettings - настройки NewCircuitBreaker, нулевые поля берутся из DefaultBreakerSettings
+type BreakerSettings struct {
+	// по скольким последним запросам считается доля сбоев
+	Window int
+	// пока запросов в окне меньше, breaker не открывается
+	MinRequests int
+	// при какой доле сбоев в окне breaker открывается, от 0 до 1
+	FailureRate float64
+	// сколько breaker остаётся открытым, прежде чем пропустить пробные запросы
+	CoolDown time.Duration
+	// сколько пробных запросов должно пройти, чтобы breaker закрылся
+	HalfOpenRequests int
+	// вызывается при каждой смене состояния, не под блокировкой breaker'а
+	OnStateChange func(from, to BreakerState)
+}
+
+// DefaultBreakerSettings - настройки по умолчанию
+var DefaultBreakerSettings = BreakerSettings{
+	Window:           20,
+	MinRequests:      5,
+	FailureRate:      0.5,
+	CoolDown:         5 * time.Second,
+	HalfOpenRequests: 1,
+}
+
+// CircuitBreaker не даёт копить запросы к упавшей внешней системе: после серии сбоев
+// запросы сразу завершаются ошибкой ErrCircuitOpen. Сбоем считаются только ошибки,
+// которые говорят о недоступности системы: таймауты, 5xx, оборванные соединения.
+// Один CircuitBreaker можно разделять между несколькими SearchClient
+type CircuitBreaker struct {
+	settings BreakerSettings
+	now      func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	results  []bool // кольцо результатов в закрытом состоянии, true - сбой
+	next     int
+	count    int
+	failures int
+	openedAt time.Time
+	probes   int // пробных запросов в half-open начато
+	passed   int // и успешно завершено
+}
+
+// NewCircuitBreaker создаёт закрытый breaker
+func NewCircuitBreaker(settings BreakerSettings) *CircuitBreaker {
+	if settings.Window <= 0 {
+		settings.Window = DefaultBreakerSettings.Window
+	}
+	if settings.MinRequests <= 0 {
+		settings.MinRequests = DefaultBreakerSettings.MinRequests
+	}
+	if settings.FailureRate <= 0 {
+		settings.FailureRate = DefaultBreakerSettings.FailureRate
+	}
+	if settings.CoolDown <= 0 {
+		settings.CoolDown = DefaultBreakerSettings.CoolDown
+	}
+	if settings.HalfOpenRequests <= 0 {
+		settings.HalfOpenRequests = DefaultBreakerSettings.HalfOpenRequests
+	}
+	return &CircuitBreaker{
+		settings: settings,
+		now:      time.Now,
+		results:  make([]bool, settings.Window),
+	}
+}
+
+// State - текущее состояние. Открытый breaker переходит в half-open только при следующем запросе
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// allow решает, пропускать ли запрос
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	from := b.state
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.settings.CoolDown {
+		b.setState(BreakerHalfOpen)
+	}
+	err := error(nil)
+	switch {
+	case b.state == BreakerOpen:
+		err = ErrCircuitOpen
+	case b.state == BreakerHalfOpen && b.probes >= b.settings.HalfOpenRequests:
+		err = ErrCircuitOpen
+	case b.state == BreakerHalfOpen:
+		b.probes++
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+	return err
+}
+
+// record учитывает результат пропущенного запроса
+func (b *CircuitBreaker) record(err error) {
+	// отмена вызывающим ничего не говорит о внешней системе
+	var ctxErr *ContextError
+	ignored := errors.As(err, &ctxErr)
+	failure := err != nil && !ignored && breakerFailure(err)
+
+	b.mu.Lock()
+	from := b.state
+	switch b.state {
+	case BreakerHalfOpen:
+		switch {
+		case ignored:
+			b.probes--
+		case failure:
+			b.setState(BreakerOpen)
+		default:
+			b.passed++
+			if b.passed >= b.settings.HalfOpenRequests {
+				b.setState(BreakerClosed)
+			}
+		}
+	case BreakerClosed:
+		if ignored {
+			break
+		}
+		if b.count == len(b.results) {
+			if b.results[b.next] {
+				b.failures--
+			}
+		} else {
+			b.count++
+		}
+		b.results[b.next] = failure
+		b.next = (b.next + 1) % len(b.results)
+		if failure {
+			b.failures++
+		}
+		if b.count >= b.settings.MinRequests && float64(b.failures) >= b.settings.FailureRate*float64(b.count) {
+			b.setState(BreakerOpen)
+		}
+	}
+	// в открытом состоянии это запоздавший ответ на запрос, начатый до открытия
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+// breakerFailure - говорит ли ошибка о том, что внешняя система лежит. В отличие от retryable,
+// сюда попадают и отказы в соединении, которые повторять бессмысленно, а исчерпанная квота - нет
+func breakerFailure(err error) bool {
+	var transportErr *transportError
+	return errors.Is(err, ErrTimeout) || errors.Is(err, ErrServerFatal) || errors.As(err, &transportErr)
+}
+
+// setState меняет состояние и сбрасывает счётчики, вызывается под mu
+func (b *CircuitBreaker) setState(state BreakerState) {
+	b.state = state
+	b.probes, b.passed = 0, 0
+	switch state {
+	case BreakerOpen:
+		b.openedAt = b.now()
+	case BreakerClosed:
+		b.next, b.count, b.failures = 0, 0, 0
+	}
+}
+
+func (b *CircuitBreaker) notify(from, to BreakerState) {
+	if from != to && b.settings.OnStateChange != nil {
+		b.settings.OnStateChange(from, to)
+	}
+}
+
+// withBreaker пропускает attempt через Breaker клиента, если он задан
+func (srv *SearchClient) withBreaker(attempt func() (*SearchResponse, error)) (*SearchResponse, error) {
+	if srv.Breaker == nil {
+		return attempt()
+	}
+	if err := srv.Breaker.allow(); err != nil {
+		return nil, err
+	}
+	resp, err := attempt()
+	srv.Breaker.record(err)
+	return resp, err
+}
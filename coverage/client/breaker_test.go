@@ -0,0 +1,133 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"coverage/client"
+)
+
+var errUnavailable = &client.SearchError{Kind: client.ErrServerFatal, StatusCode: http.StatusServiceUnavailable}
+
+func TestCircuitBreaker(t *testing.T) {
+	var changes []string
+	b := client.NewCircuitBreaker(client.BreakerSettings{
+		Window:           4,
+		MinRequests:      4,
+		FailureRate:      0.5,
+		CoolDown:         time.Minute,
+		HalfOpenRequests: 2,
+		OnStateChange: func(from, to client.BreakerState) {
+			changes = append(changes, from.String()+"->"+to.String())
+		},
+	})
+	now := time.Now()
+	b.SetNow(func() time.Time { return now })
+
+	call := func(err error) error {
+		if err := b.Allow(); err != nil {
+			return err
+		}
+		b.Record(err)
+		return nil
+	}
+
+	// постоянные ошибки, отмены и исчерпанная квота сбоями не считаются
+	for _, err := range []error{
+		nil,
+		errUnavailable,
+		&client.SearchError{Kind: client.ErrBadRequest},
+		client.NewContextError(context.Canceled),
+		&client.SearchError{Kind: client.ErrRateLimited},
+	} {
+		call(err)
+	}
+	if b.State() != client.BreakerClosed {
+		t.Fatalf("wrong result, expected closed, got %v", b.State())
+	}
+	call(errUnavailable)
+	if b.State() != client.BreakerOpen || !errors.Is(call(nil), client.ErrCircuitOpen) {
+		t.Fatalf("wrong result, expected open, got %v", b.State())
+	}
+
+	// после CoolDown пропускаем два пробных запроса, третий ждёт
+	now = now.Add(time.Minute)
+	if b.Allow() != nil || b.Allow() != nil || !errors.Is(b.Allow(), client.ErrCircuitOpen) {
+		t.Fatalf("wrong result, expected two probes in half-open")
+	}
+	b.Record(nil)
+	b.Record(errUnavailable)
+	if b.State() != client.BreakerOpen {
+		t.Fatalf("wrong result, expected open after failed probe, got %v", b.State())
+	}
+
+	now = now.Add(time.Minute)
+	call(nil)
+	call(nil)
+	if b.State() != client.BreakerClosed {
+		t.Fatalf("wrong result, expected closed after probes, got %v", b.State())
+	}
+
+	expected := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if !reflect.DeepEqual(expected, changes) {
+		t.Errorf("wrong result, expected %v, got %v", expected, changes)
+	}
+}
+
+func TestBreakerFailFast(t *testing.T) {
+	var requests int32
+	downTs := flakyServer(100, &requests, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	defer downTs.Close()
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         downTs.URL,
+		Retry:       &client.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+		Breaker:     client.NewCircuitBreaker(client.BreakerSettings{Window: 4, MinRequests: 4, CoolDown: time.Minute}),
+	}
+
+	// первый вызов делает три попытки, на второй breaker открывается и повторы прекращаются
+	_, err := s.FindUsers(client.SearchRequest{})
+	if !errors.Is(err, client.ErrServerFatal) {
+		t.Errorf("wrong result, expected %v, got %v", client.ErrServerFatal, err)
+	}
+	_, err = s.FindUsers(client.SearchRequest{})
+	if !errors.Is(err, client.ErrCircuitOpen) || atomic.LoadInt32(&requests) != 4 {
+		t.Errorf("wrong result, expected %v after 4 requests, got %v after %d", client.ErrCircuitOpen, err, requests)
+	}
+
+	began := time.Now()
+	_, err = s.FindUsers(client.SearchRequest{})
+	if !errors.Is(err, client.ErrCircuitOpen) || atomic.LoadInt32(&requests) != 4 || time.Since(began) > 10*time.Millisecond {
+		t.Errorf("wrong result, expected to fail fast, got %v after %d requests", err, requests)
+	}
+}
+
+func TestBreakerConnectionRefused(t *testing.T) {
+	downTs := httptest.NewServer(http.NotFoundHandler())
+	downURL := downTs.URL
+	downTs.Close()
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         downURL,
+		Breaker:     client.NewCircuitBreaker(client.BreakerSettings{Window: 4, MinRequests: 2, CoolDown: time.Minute}),
+	}
+
+	// сервер лежит целиком: соединение отвергнуто, хотя повторять такое retryable не велит
+	for i := 0; i < 2; i++ {
+		if _, err := s.FindUsers(client.SearchRequest{}); err == nil || !strings.Contains(err.Error(), "unknown error") {
+			t.Fatalf("[%d] wrong result, expected unknown error, got %v", i, err)
+		}
+	}
+	if _, err := s.FindUsers(client.SearchRequest{}); !errors.Is(err, client.ErrCircuitOpen) {
+		t.Errorf("wrong result, expected %v, got %v", client.ErrCircuitOpen, err)
+	}
+}
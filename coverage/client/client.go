@@ -0,0 +1,365 @@
+// Package client - клиент SearchServer: SearchClient и общие с сервером типы запросов и ответов
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	orderAsc = iota
+	orderDesc
+)
+
+// DefaultTimeout - таймаут запроса, если у SearchClient не заданы ни Timeout, ни HTTPClient
+const DefaultTimeout = time.Second
+
+var (
+	errTest = errors.New("testing")
+	// 200 с объектом без users, например {"error": ...}, - не пустая страница
+	errNoUsers = errors.New("no users in response")
+	client     = &http.Client{Timeout: DefaultTimeout}
+	// при заданном SearchClient.Timeout срок ставит контекст, общий таймаут только урезал бы его
+	untimedClient = &http.Client{}
+)
+
+type User struct {
+	Id     int
+	Name   string
+	Age    int
+	About  string
+	Gender string
+	// релевантность запросу, заполняется только при сортировке по Relevance
+	Score float64 `json:",omitempty"`
+
+	// остальные поля из row приходят, только если их перечислили в SearchRequest.Fields.
+	// IsActive - указатель, чтобы false отличался от незапрошенного поля
+	GUID          string `json:",omitempty"`
+	IsActive      *bool  `json:",omitempty"`
+	Balance       string `json:",omitempty"`
+	Picture       string `json:",omitempty"`
+	EyeColor      string `json:",omitempty"`
+	Company       string `json:",omitempty"`
+	Email         string `json:",omitempty"`
+	Phone         string `json:",omitempty"`
+	Address       string `json:",omitempty"`
+	Registered    string `json:",omitempty"`
+	FavoriteFruit string `json:",omitempty"`
+}
+
+type SearchResponse struct {
+	Users    []User
+	NextPage bool
+	// курсоры для SearchRequest.Cursor: следующая и предыдущая страницы того же размера.
+	// Пустые, если таких страниц нет или сервер курсоры не поддерживает
+	NextCursor string
+	PrevCursor string
+
+	// всего найдено с учётом фильтров; -1, если старый сервер этого не сообщает
+	Total int
+	// с какой записи и сколько записей на страницу, чтобы показать "страница 3 из 12".
+	// При переходе по курсору Offset - настоящее смещение страницы
+	Offset int
+	Limit  int
+	// сколько поиск занял на сервере
+	Took time.Duration
+}
+
+// SearchEnvelope - тело успешного ответа сервера. Старые серверы отдают вместо него
+// просто массив пользователей, FindUsers понимает оба варианта
+type SearchEnvelope struct {
+	Users      []User  `json:"users"`
+	Total      int     `json:"total"`
+	Offset     int     `json:"offset"`
+	Limit      int     `json:"limit"`
+	HasMore    bool    `json:"has_more"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+	PrevCursor string  `json:"prev_cursor,omitempty"`
+	TookMs     float64 `json:"took_ms"`
+}
+
+type SearchErrorResponse struct {
+	Error string
+}
+
+const (
+	// значения OrderBy. Поля User сервер по давней традиции сортирует при 1 по возрастанию,
+	// при -1 по убыванию; Relevance - по именам: OrderByDesc - сначала самые релевантные
+	OrderByAsc  = -1
+	OrderByAsIs = 0
+	OrderByDesc = 1
+
+	ErrorBadOrderField = `OrderField invalid`
+
+	FieldsAll = "*"
+
+	// больше за один запрос не отдаётся, Limit урезается до этого значения
+	MaxLimit = 25
+)
+
+type SearchRequest struct {
+	Limit      int
+	Offset     int    // Можно учесть после сортировки
+	Query      string // подстрока в 1 из полей
+	OrderField string
+	// -1 по убыванию, 0 как встретилось, 1 по возрастанию; для Relevance наоборот, см. OrderByDesc
+	OrderBy int
+	// какие дополнительные поля User вернуть, например Email, Company; FieldsAll - все
+	Fields []string
+	// условия на поля, применяются до сортировки и пагинации
+	Filters []Filter
+	// по умолчанию слова Query сравниваются в нижнем регистре и без диакритики, true - искать точно
+	CaseSensitive bool
+	// искать имена и с опечатками, точные совпадения всё равно идут первыми
+	Fuzzy bool
+	// несколько ключей сортировки по любым полям User; если задан, OrderField и OrderBy не учитываются
+	Sort []SortKey
+	// курсор из SearchResponse.NextCursor или PrevCursor, используется вместо Offset.
+	// Остальные поля запроса должны быть теми же, что и при получении курсора
+	Cursor string
+}
+
+// SortKey - поле User (регистр не важен, Relevance - по Score) и направление сортировки
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// String - вид, в котором ключ уходит в параметре sort: "Age desc"
+func (k SortKey) String() string {
+	if k.Desc {
+		return k.Field + " desc"
+	}
+	return k.Field + " asc"
+}
+
+// операторы для Filter
+const (
+	FilterEq  = "="
+	FilterNe  = "!="
+	FilterGt  = ">"
+	FilterGte = ">="
+	FilterLt  = "<"
+	FilterLte = "<="
+	// Value для FilterIn - значения через запятую
+	FilterIn = "in"
+)
+
+// Filter - условие на поле пользователя, имя поля как в dataset.xml:
+// {"age", FilterGte, "30"}, {"eyeColor", FilterIn, "blue,green"}, {"registered", FilterLt, "2015-01-01"}
+type Filter struct {
+	Field string
+	Op    string
+	Value string
+}
+
+// String - вид, в котором фильтр уходит в параметре filter
+func (f Filter) String() string {
+	if f.Op == FilterIn {
+		return f.Field + " " + FilterIn + " (" + f.Value + ")"
+	}
+	return f.Field + f.Op + f.Value
+}
+
+type SearchClient struct {
+	// токен, по которому происходит авторизация на внешней системе, уходит туда через хедер
+	AccessToken string
+	// урл внешней системы, куда идти
+	URL string
+	// чем ходить во внешнюю систему; nil - общий клиент с таймаутом Timeout или DefaultTimeout
+	HTTPClient *http.Client
+	// сколько ждать ответа на один запрос; 0 - сколько позволит HTTPClient
+	Timeout time.Duration
+	// повторы после временных сбоев; nil - без повторов
+	Retry *RetryPolicy
+	// пока открыт, запросы сразу получают ErrCircuitOpen; nil - без breaker'а
+	Breaker *CircuitBreaker
+	// если задан, AccessToken берётся отсюда перед каждым запросом, например из JWTMinter
+	TokenSource TokenSource
+}
+
+// FindUsers отправляет запрос во внешнюю систему, которая непосредственно ищет пользователей
+func (srv *SearchClient) FindUsers(req SearchRequest) (*SearchResponse, error) {
+	return srv.FindUsersContext(context.Background(), req)
+}
+
+// FindUsersContext - FindUsers, который можно отменить через ctx или ограничить его сроком.
+// В этом случае возвращается *ContextError. При заданном Retry временные сбои повторяются,
+// каждая попытка проходит через Breaker
+func (srv *SearchClient) FindUsersContext(ctx context.Context, req SearchRequest) (*SearchResponse, error) {
+	return srv.withRetry(ctx, func() (*SearchResponse, error) {
+		return srv.withBreaker(func() (*SearchResponse, error) {
+			return srv.findUsers(ctx, req)
+		})
+	})
+}
+
+// findUsers - одна попытка запроса
+func (srv *SearchClient) findUsers(ctx context.Context, req SearchRequest) (*SearchResponse, error) {
+
+	searcherParams := url.Values{}
+
+	if req.Limit < 0 {
+		return nil, fmt.Errorf("limit must be > 0")
+	}
+	if req.Limit > MaxLimit {
+		req.Limit = MaxLimit
+	}
+	if req.Offset < 0 {
+		return nil, fmt.Errorf("offset must be > 0")
+	}
+
+	// старый сервер знает только limit: просим на запись больше и по ней узнаём, есть ли
+	// следующая страница. Новый берёт размер страницы из page_limit и сам сообщает HasMore
+	searcherParams.Add("limit", strconv.Itoa(req.Limit+1))
+	searcherParams.Add("page_limit", strconv.Itoa(req.Limit))
+	searcherParams.Add("offset", strconv.Itoa(req.Offset))
+	searcherParams.Add("query", req.Query)
+	searcherParams.Add("order_field", req.OrderField)
+	searcherParams.Add("order_by", strconv.Itoa(req.OrderBy))
+	if len(req.Fields) > 0 {
+		searcherParams.Add("fields", strings.Join(req.Fields, ","))
+	}
+	for _, f := range req.Filters {
+		searcherParams.Add("filter", f.String())
+	}
+	if req.CaseSensitive {
+		searcherParams.Add("case_sensitive", "true")
+	}
+	if req.Fuzzy {
+		searcherParams.Add("fuzzy", "true")
+	}
+	if req.Cursor != "" {
+		searcherParams.Add("cursor", req.Cursor)
+	}
+	if len(req.Sort) > 0 {
+		keys := make([]string, 0, len(req.Sort))
+		for _, key := range req.Sort {
+			keys = append(keys, key.String())
+		}
+		searcherParams.Add("sort", strings.Join(keys, ","))
+	}
+
+	callCtx := ctx
+	if srv.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, srv.Timeout)
+		defer cancel()
+	}
+	searcherReq, _ := http.NewRequestWithContext(callCtx, "GET", srv.URL+"?"+searcherParams.Encode(), nil)
+	accessToken := srv.AccessToken
+	if srv.TokenSource != nil {
+		var err error
+		if accessToken, err = srv.TokenSource.AccessToken(); err != nil {
+			return nil, fmt.Errorf("can't get AccessToken: %w", err)
+		}
+	}
+	searcherReq.Header.Add("AccessToken", accessToken)
+
+	httpClient := srv.HTTPClient
+	if httpClient == nil {
+		httpClient = client
+		if srv.Timeout > 0 {
+			httpClient = untimedClient
+		}
+	}
+	resp, err := httpClient.Do(searcherReq)
+	if err != nil {
+		// отмену и срок вызывающего отличаем от таймаута самого клиента
+		if ctx.Err() != nil {
+			return nil, newContextError(ctx.Err())
+		}
+		var netErr net.Error
+		if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, newSearchError(ErrTimeout, 0, nil, err, "timeout for %s", searcherParams.Encode())
+		}
+		return nil, &transportError{err}
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		fatal := newSearchError(ErrServerFatal, resp.StatusCode, body, nil, "SearchServer fatal error")
+		fatal.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		return nil, fatal
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		limited := newSearchError(ErrRateLimited, resp.StatusCode, body, nil, "rate limit exceeded, retry after %s", resp.Header.Get("Retry-After"))
+		limited.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		return nil, limited
+	case http.StatusUnauthorized, http.StatusForbidden:
+		authErr := newSearchError(ErrUnauthorized, resp.StatusCode, body, nil, "Bad AccessToken")
+		if resp.StatusCode == http.StatusForbidden {
+			authErr = newSearchError(ErrForbidden, resp.StatusCode, body, nil, "AccessToken has no access")
+		}
+		// старые серверы присылали текст, новые - SearchErrorResponse
+		errResp := SearchErrorResponse{}
+		if json.Unmarshal(body, &errResp) == nil {
+			authErr.Message = errResp.Error
+		}
+		return nil, authErr
+	case http.StatusBadRequest:
+		errResp := SearchErrorResponse{}
+		err = json.Unmarshal(body, &errResp)
+		if err != nil {
+			return nil, newSearchError(ErrDecode, resp.StatusCode, body, err, "cant unpack error json: %s", err)
+		}
+		var badRequest *SearchError
+		if errResp.Error == "ErrorBadOrderField" {
+			badRequest = newSearchError(ErrBadOrderField, resp.StatusCode, body, nil, "OrderFeld %s invalid", req.OrderField)
+		} else {
+			badRequest = newSearchError(ErrBadRequest, resp.StatusCode, body, nil, "unknown bad request error: %s", errResp.Error)
+		}
+		badRequest.Message = errResp.Error
+		return nil, badRequest
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, newSearchError(ErrUnexpectedStatus, resp.StatusCode, body, nil, "unexpected status %d", resp.StatusCode)
+	}
+
+	result := SearchResponse{
+		Total:  -1,
+		Offset: req.Offset,
+		Limit:  req.Limit,
+	}
+	data := []User{}
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		// старый формат - голый массив. Есть ли ещё, он не сообщает: об этом говорит
+		// лишняя запись, запрошенная через limit
+		err = json.Unmarshal(trimmed, &data)
+		if len(data) > req.Limit {
+			result.NextPage = true
+			data = data[:req.Limit]
+		}
+	} else {
+		envelope := SearchEnvelope{}
+		err = json.Unmarshal(body, &envelope)
+		if err == nil && envelope.Users == nil {
+			err = errNoUsers
+		}
+		data = envelope.Users
+		result.NextPage = envelope.HasMore
+		result.Total = envelope.Total
+		result.Offset = envelope.Offset
+		result.NextCursor = envelope.NextCursor
+		result.PrevCursor = envelope.PrevCursor
+		result.Took = time.Duration(envelope.TookMs * float64(time.Millisecond))
+	}
+	if err != nil {
+		return nil, newSearchError(ErrDecode, resp.StatusCode, body, err, "cant unpack result json: %s", err)
+	}
+
+	result.Users = data
+	return &result, err
+}
@@ -1,6 +1,7 @@
-package main
+package client_test
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -8,45 +9,51 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
+
+	"coverage/client"
+	"coverage/server"
 )
 
 /*
-	COVER
-	go test -coverprofile=cover.out
-	go tool cover -html=cover.out -o cover.html
+COVER
+go test -coverprofile=cover.out
+go tool cover -html=cover.out -o cover.html
 */
 var (
-	ts = httptest.NewServer(http.HandlerFunc(SearchServer))
+	searchServer = server.New(server.NewXMLFileStore("../dataset.xml"), tokensFile, server.Options{})
+	ts           = httptest.NewServer(searchServer)
 )
 
 const (
-	token = "AccessToken"
+	token      = "AccessToken"
+	tokensFile = "../testdata/tokens.json"
 )
 
 type Result struct {
-	Response *SearchResponse
+	Response *client.SearchResponse
 	Error    error
 }
 
 type TestCase struct {
-	Request SearchRequest
+	Request client.SearchRequest
 	Result  Result
 }
 
 func TestGreaterLimit(t *testing.T) {
 	maximum := 25
 	test := TestCase{
-		Request: SearchRequest{
+		Request: client.SearchRequest{
 			Limit: maximum + 1,
 		},
 	}
 
-	s := &SearchClient{
-		token,
-		ts.URL,
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         ts.URL,
 	}
 	result, err := s.FindUsers(test.Request)
 
@@ -61,6 +68,7 @@ func TestGreaterLimit(t *testing.T) {
 type TestCaseServer struct {
 	function func(w http.ResponseWriter, r *http.Request)
 	result   string
+	kind     error
 }
 
 func TestServerErr(t *testing.T) {
@@ -72,6 +80,7 @@ func TestServerErr(t *testing.T) {
 				return
 			},
 			result: "timeout",
+			kind:   client.ErrTimeout,
 		},
 		{
 			function: func(w http.ResponseWriter, r *http.Request) {
@@ -79,6 +88,7 @@ func TestServerErr(t *testing.T) {
 				return
 			},
 			result: "SearchServer fatal error",
+			kind:   client.ErrServerFatal,
 		},
 		{
 			function: func(w http.ResponseWriter, r *http.Request) {
@@ -87,6 +97,7 @@ func TestServerErr(t *testing.T) {
 				return
 			},
 			result: "cant unpack error json",
+			kind:   client.ErrDecode,
 		},
 		{
 			function: func(w http.ResponseWriter, r *http.Request) {
@@ -94,15 +105,30 @@ func TestServerErr(t *testing.T) {
 				return
 			},
 			result: "cant unpack result json",
+			kind:   client.ErrDecode,
+		},
+		{
+			function: func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, `{"error": "boom"}`)
+			},
+			result: "cant unpack result json: no users in response",
+			kind:   client.ErrDecode,
+		},
+		{
+			function: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			},
+			result: "unexpected status 418",
+			kind:   client.ErrUnexpectedStatus,
 		},
 	}
 	for caseNum, testItem := range tests {
 		tmpTs := httptest.NewServer(http.HandlerFunc(testItem.function))
-		s := &SearchClient{
-			token,
-			tmpTs.URL,
+		s := &client.SearchClient{
+			AccessToken: token,
+			URL:         tmpTs.URL,
 		}
-		request := SearchRequest{}
+		request := client.SearchRequest{}
 		_, err := s.FindUsers(request)
 
 		if err == nil {
@@ -111,25 +137,99 @@ func TestServerErr(t *testing.T) {
 		if !strings.Contains(err.Error(), testItem.result) {
 			t.Errorf("[%v] wrong result, got %#v", caseNum, err.Error())
 		}
+		if !errors.Is(err, testItem.kind) {
+			t.Errorf("[%v] wrong result, expected %v, got %#v", caseNum, testItem.kind, err)
+		}
 
 		tmpTs.Close()
 	}
 }
+
+// slowServer отвечает SearchServer'ом через delay, если клиент столько подождёт
+func slowServer(delay time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+			searchServer.ServeHTTP(w, r)
+		case <-r.Context().Done():
+		}
+	}))
+}
+
+func TestFindUsersContext(t *testing.T) {
+	slowTs := slowServer(200 * time.Millisecond)
+	defer slowTs.Close()
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         slowTs.URL,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err := s.FindUsersContext(ctx, client.SearchRequest{})
+	var ctxErr *client.ContextError
+	if !errors.Is(err, client.ErrCanceled) || !errors.Is(err, context.Canceled) || !errors.As(err, &ctxErr) {
+		t.Errorf("wrong result, expected canceled error, got %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = s.FindUsersContext(ctx, client.SearchRequest{})
+	if !errors.Is(err, client.ErrDeadlineExceeded) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wrong result, expected deadline error, got %v", err)
+	}
+
+	// свой таймаут клиента - не ошибка контекста вызывающего
+	s.Timeout = 50 * time.Millisecond
+	_, err = s.FindUsersContext(context.Background(), client.SearchRequest{})
+	if err == nil || !strings.Contains(err.Error(), "timeout") || errors.As(err, &ctxErr) {
+		t.Errorf("wrong result, expected timeout error, got %v", err)
+	}
+
+	s.Timeout = time.Second
+	if _, err = s.FindUsersContext(context.Background(), client.SearchRequest{}); err != nil {
+		t.Errorf("expected nil, got error: %v", err)
+	}
+}
+
+func TestHTTPClient(t *testing.T) {
+	slowTs := slowServer(client.DefaultTimeout + 200*time.Millisecond)
+	defer slowTs.Close()
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         slowTs.URL,
+		HTTPClient:  &http.Client{Timeout: 2 * client.DefaultTimeout},
+	}
+	if _, err := s.FindUsers(client.SearchRequest{}); err != nil {
+		t.Errorf("expected nil, got error: %v", err)
+	}
+
+	// Timeout больше DefaultTimeout действует и без своего HTTPClient
+	s = &client.SearchClient{
+		AccessToken: token,
+		URL:         slowTs.URL,
+		Timeout:     2 * client.DefaultTimeout,
+	}
+	if _, err := s.FindUsers(client.SearchRequest{}); err != nil {
+		t.Errorf("expected nil, got error: %v", err)
+	}
+}
+
 func TestUnknownErrorBadAccess(t *testing.T) {
-	tests := []SearchClient{
+	tests := []client.SearchClient{
 		{
-			token,
-			"",
+			AccessToken: token,
+			URL:         "",
 		},
 		{
-			"bad",
-			ts.URL,
+			AccessToken: "bad",
+			URL:         ts.URL,
 		},
 	}
 
 	for caseNum, testItem := range tests {
 		s := &testItem
-		request := SearchRequest{}
+		request := client.SearchRequest{}
 		_, err := s.FindUsers(request)
 
 		if err == nil {
@@ -160,12 +260,30 @@ func TestServerStatusBadRequest(t *testing.T) {
 			request: "limit=1&offset=0&query=&order_field=&order_by=\"\"",
 			result:  "no order_by in request",
 		},
+		{
+			request: "limit=-1&offset=0&query=&order_field=&order_by=0",
+			result:  "limit must not be negative",
+		},
+		{
+			request: "limit=1&offset=-1&query=&order_field=&order_by=0",
+			result:  "offset must not be negative",
+		},
+		{
+			request: "limit=2&page_limit=x&offset=0&query=&order_field=&order_by=0",
+			result:  "bad page_limit in request",
+		},
+		{
+			request: "limit=2&page_limit=-1&offset=0&query=&order_field=&order_by=0",
+			result:  "limit must not be negative",
+		},
 	}
 
 	for caseNum, testItem := range tests {
-		resp, _ := http.Get(ts.URL + "?" + testItem.request)
+		req, _ := http.NewRequest("GET", ts.URL+"?"+testItem.request, nil)
+		req.Header.Set("AccessToken", token)
+		resp, _ := http.DefaultClient.Do(req)
 		body, _ := ioutil.ReadAll(resp.Body)
-		errResp := SearchErrorResponse{}
+		errResp := client.SearchErrorResponse{}
 		_ = json.Unmarshal(body, &errResp)
 		if errResp.Error != testItem.result {
 			t.Errorf("[%d] wrong result, got %#v", caseNum, errResp.Error)
@@ -176,7 +294,7 @@ func TestServerStatusBadRequest(t *testing.T) {
 func TestStatusBadRequestErrorBadOrderField(t *testing.T) {
 	tests := []TestCase{
 		{
-			Request: SearchRequest{
+			Request: client.SearchRequest{
 				OrderBy:    1,
 				OrderField: "N",
 			},
@@ -186,7 +304,7 @@ func TestStatusBadRequestErrorBadOrderField(t *testing.T) {
 			},
 		},
 		{
-			Request: SearchRequest{
+			Request: client.SearchRequest{
 				OrderBy:    -1,
 				OrderField: "N",
 			},
@@ -196,7 +314,7 @@ func TestStatusBadRequestErrorBadOrderField(t *testing.T) {
 			},
 		},
 		{
-			Request: SearchRequest{
+			Request: client.SearchRequest{
 				OrderBy: 2,
 			},
 			Result: Result{
@@ -205,7 +323,7 @@ func TestStatusBadRequestErrorBadOrderField(t *testing.T) {
 			},
 		},
 		{
-			Request: SearchRequest{
+			Request: client.SearchRequest{
 				Limit: -1,
 			},
 			Result: Result{
@@ -214,7 +332,7 @@ func TestStatusBadRequestErrorBadOrderField(t *testing.T) {
 			},
 		},
 		{
-			Request: SearchRequest{
+			Request: client.SearchRequest{
 				Offset: -1,
 			},
 			Result: Result{
@@ -225,9 +343,9 @@ func TestStatusBadRequestErrorBadOrderField(t *testing.T) {
 	}
 
 	for caseNum, testItem := range tests {
-		s := &SearchClient{
-			token,
-			ts.URL,
+		s := &client.SearchClient{
+			AccessToken: token,
+			URL:         ts.URL,
 		}
 		_, err := s.FindUsers(testItem.Request)
 
@@ -240,32 +358,64 @@ func TestStatusBadRequestErrorBadOrderField(t *testing.T) {
 	}
 }
 
+func TestSearchErrorDetails(t *testing.T) {
+	tests := []struct {
+		token   string
+		request client.SearchRequest
+		kind    error
+		status  int
+		message string
+	}{
+		{"bad", client.SearchRequest{}, client.ErrUnauthorized, http.StatusUnauthorized, "bad AccessToken"},
+		{"ExpiredToken", client.SearchRequest{}, client.ErrUnauthorized, http.StatusUnauthorized, "AccessToken expired"},
+		{token, client.SearchRequest{OrderBy: 1, OrderField: "N"}, client.ErrBadOrderField, http.StatusBadRequest, "ErrorBadOrderField"},
+		{token, client.SearchRequest{OrderBy: 2}, client.ErrBadRequest, http.StatusBadRequest, "have no such sort parameter"},
+	}
+
+	for caseNum, testItem := range tests {
+		s := &client.SearchClient{
+			AccessToken: testItem.token,
+			URL:         ts.URL,
+		}
+		_, err := s.FindUsers(testItem.request)
+		var searchErr *client.SearchError
+		if !errors.Is(err, testItem.kind) || !errors.As(err, &searchErr) {
+			t.Errorf("[%d] wrong result, expected %v, got %#v", caseNum, testItem.kind, err)
+			continue
+		}
+		if searchErr.StatusCode != testItem.status || searchErr.Message != testItem.message || len(searchErr.Body) == 0 {
+			t.Errorf("[%d] wrong result, got status %d, message %q, body %q",
+				caseNum, searchErr.StatusCode, searchErr.Message, searchErr.Body)
+		}
+	}
+}
+
 func TestOrderField(t *testing.T) {
 	tests := []TestCase{
 		{
-			Request: SearchRequest{
+			Request: client.SearchRequest{
 				Limit:      3,
 				OrderBy:    1,
 				OrderField: "Id",
 			},
 			Result: Result{
-				&SearchResponse{
-					Users: []User{
-						User{
+				&client.SearchResponse{
+					Users: []client.User{
+						client.User{
 							Id:     0,
 							Name:   "Boyd Wolf",
 							Age:    22,
 							About:  "Nulla cillum enim voluptate consequat laborum esse excepteur occaecat commodo nostrud excepteur ut cupidatat. Occaecat minim incididunt ut proident ad sint nostrud ad laborum sint pariatur. Ut nulla commodo dolore officia. Consequat anim eiusmod amet commodo eiusmod deserunt culpa. Ea sit dolore nostrud cillum proident nisi mollit est Lorem pariatur. Lorem aute officia deserunt dolor nisi aliqua consequat nulla nostrud ipsum irure id deserunt dolore. Minim reprehenderit nulla exercitation labore ipsum.\n",
 							Gender: "male",
 						},
-						User{
+						client.User{
 							Id:     1,
 							Name:   "Hilda Mayer",
 							Age:    21,
 							About:  "Sit commodo consectetur minim amet ex. Elit aute mollit fugiat labore sint ipsum dolor cupidatat qui reprehenderit. Eu nisi in exercitation culpa sint aliqua nulla nulla proident eu. Nisi reprehenderit anim cupidatat dolor incididunt laboris mollit magna commodo ex. Cupidatat sit id aliqua amet nisi et voluptate voluptate commodo ex eiusmod et nulla velit.\n",
 							Gender: "female",
 						},
-						User{
+						client.User{
 							Id:     2,
 							Name:   "Brooks Aguilar",
 							Age:    25,
@@ -274,34 +424,36 @@ func TestOrderField(t *testing.T) {
 						},
 					},
 					NextPage: true,
+					Total:    35,
+					Limit:    3,
 				},
 				nil,
 			},
 		},
 		{
-			Request: SearchRequest{
+			Request: client.SearchRequest{
 				Limit:      3,
 				OrderBy:    1,
 				OrderField: "",
 			},
 			Result: Result{
-				&SearchResponse{
-					Users: []User{
-						User{
+				&client.SearchResponse{
+					Users: []client.User{
+						client.User{
 							Id:     15,
 							Name:   "Allison Valdez",
 							Age:    21,
 							About:  "Labore excepteur voluptate velit occaecat est nisi minim. Laborum ea et irure nostrud enim sit incididunt reprehenderit id est nostrud eu. Ullamco sint nisi voluptate cillum nostrud aliquip et minim. Enim duis esse do aute qui officia ipsum ut occaecat deserunt. Pariatur pariatur nisi do ad dolore reprehenderit et et enim esse dolor qui. Excepteur ullamco adipisicing qui adipisicing tempor minim aliquip.\n",
 							Gender: "male",
 						},
-						User{
+						client.User{
 							Id:     16,
 							Name:   "Annie Osborn",
 							Age:    35,
 							About:  "Consequat fugiat veniam commodo nisi nostrud culpa pariatur. Aliquip velit adipisicing dolor et nostrud. Eu nostrud officia velit eiusmod ullamco duis eiusmod ad non do quis.\n",
 							Gender: "female",
 						},
-						User{
+						client.User{
 							Id:     19,
 							Name:   "Bell Bauer",
 							Age:    26,
@@ -310,34 +462,36 @@ func TestOrderField(t *testing.T) {
 						},
 					},
 					NextPage: true,
+					Total:    35,
+					Limit:    3,
 				},
 				nil,
 			},
 		},
 		{
-			Request: SearchRequest{
+			Request: client.SearchRequest{
 				Limit:      3,
 				OrderBy:    1,
 				OrderField: "Name",
 			},
 			Result: Result{
-				&SearchResponse{
-					Users: []User{
-						User{
+				&client.SearchResponse{
+					Users: []client.User{
+						client.User{
 							Id:     15,
 							Name:   "Allison Valdez",
 							Age:    21,
 							About:  "Labore excepteur voluptate velit occaecat est nisi minim. Laborum ea et irure nostrud enim sit incididunt reprehenderit id est nostrud eu. Ullamco sint nisi voluptate cillum nostrud aliquip et minim. Enim duis esse do aute qui officia ipsum ut occaecat deserunt. Pariatur pariatur nisi do ad dolore reprehenderit et et enim esse dolor qui. Excepteur ullamco adipisicing qui adipisicing tempor minim aliquip.\n",
 							Gender: "male",
 						},
-						User{
+						client.User{
 							Id:     16,
 							Name:   "Annie Osborn",
 							Age:    35,
 							About:  "Consequat fugiat veniam commodo nisi nostrud culpa pariatur. Aliquip velit adipisicing dolor et nostrud. Eu nostrud officia velit eiusmod ullamco duis eiusmod ad non do quis.\n",
 							Gender: "female",
 						},
-						User{
+						client.User{
 							Id:     19,
 							Name:   "Bell Bauer",
 							Age:    26,
@@ -346,34 +500,36 @@ func TestOrderField(t *testing.T) {
 						},
 					},
 					NextPage: true,
+					Total:    35,
+					Limit:    3,
 				},
 				nil,
 			},
 		},
 		{
-			Request: SearchRequest{
+			Request: client.SearchRequest{
 				Limit:      3,
 				OrderBy:    1,
 				OrderField: "Age",
 			},
 			Result: Result{
-				&SearchResponse{
-					Users: []User{
-						User{
+				&client.SearchResponse{
+					Users: []client.User{
+						client.User{
 							Id:     1,
 							Name:   "Hilda Mayer",
 							Age:    21,
 							About:  "Sit commodo consectetur minim amet ex. Elit aute mollit fugiat labore sint ipsum dolor cupidatat qui reprehenderit. Eu nisi in exercitation culpa sint aliqua nulla nulla proident eu. Nisi reprehenderit anim cupidatat dolor incididunt laboris mollit magna commodo ex. Cupidatat sit id aliqua amet nisi et voluptate voluptate commodo ex eiusmod et nulla velit.\n",
 							Gender: "female",
 						},
-						User{
+						client.User{
 							Id:     15,
 							Name:   "Allison Valdez",
 							Age:    21,
 							About:  "Labore excepteur voluptate velit occaecat est nisi minim. Laborum ea et irure nostrud enim sit incididunt reprehenderit id est nostrud eu. Ullamco sint nisi voluptate cillum nostrud aliquip et minim. Enim duis esse do aute qui officia ipsum ut occaecat deserunt. Pariatur pariatur nisi do ad dolore reprehenderit et et enim esse dolor qui. Excepteur ullamco adipisicing qui adipisicing tempor minim aliquip.\n",
 							Gender: "male",
 						},
-						User{
+						client.User{
 							Id:     23,
 							Name:   "Gates Spencer",
 							Age:    21,
@@ -382,34 +538,36 @@ func TestOrderField(t *testing.T) {
 						},
 					},
 					NextPage: true,
+					Total:    35,
+					Limit:    3,
 				},
 				nil,
 			},
 		},
 		{
-			Request: SearchRequest{
+			Request: client.SearchRequest{
 				Limit:      3,
 				OrderBy:    -1,
 				OrderField: "Id",
 			},
 			Result: Result{
-				&SearchResponse{
-					Users: []User{
-						User{
+				&client.SearchResponse{
+					Users: []client.User{
+						client.User{
 							Id:     34,
 							Name:   "Kane Sharp",
 							Age:    34,
 							About:  "Lorem proident sint minim anim commodo cillum. Eiusmod velit culpa commodo anim consectetur consectetur sint sint labore. Mollit consequat consectetur magna nulla veniam commodo eu ut et. Ut adipisicing qui ex consectetur officia sint ut fugiat ex velit cupidatat fugiat nisi non. Dolor minim mollit aliquip veniam nostrud. Magna eu aliqua Lorem aliquip.\n",
 							Gender: "male",
 						},
-						User{
+						client.User{
 							Id:     33,
 							Name:   "Twila Snow",
 							Age:    36,
 							About:  "Sint non sunt adipisicing sit laborum cillum magna nisi exercitation. Dolore officia esse dolore officia ea adipisicing amet ea nostrud elit cupidatat laboris. Proident culpa ullamco aute incididunt aute. Laboris et nulla incididunt consequat pariatur enim dolor incididunt adipisicing enim fugiat tempor ullamco. Amet est ullamco officia consectetur cupidatat non sunt laborum nisi in ex. Quis labore quis ipsum est nisi ex officia reprehenderit ad adipisicing fugiat. Labore fugiat ea dolore exercitation sint duis aliqua.\n",
 							Gender: "female",
 						},
-						User{
+						client.User{
 							Id:     32,
 							Name:   "Christy Knapp",
 							Age:    40,
@@ -418,34 +576,36 @@ func TestOrderField(t *testing.T) {
 						},
 					},
 					NextPage: true,
+					Total:    35,
+					Limit:    3,
 				},
 				nil,
 			},
 		},
 		{
-			Request: SearchRequest{
+			Request: client.SearchRequest{
 				Limit:      3,
 				OrderBy:    -1,
 				OrderField: "",
 			},
 			Result: Result{
-				&SearchResponse{
-					Users: []User{
-						User{
+				&client.SearchResponse{
+					Users: []client.User{
+						client.User{
 							Id:     13,
 							Name:   "Whitley Davidson",
 							Age:    40,
 							About:  "Consectetur dolore anim veniam aliqua deserunt officia eu. Et ullamco commodo ad officia duis ex incididunt proident consequat nostrud proident quis tempor. Sunt magna ad excepteur eu sint aliqua eiusmod deserunt proident. Do labore est dolore voluptate ullamco est dolore excepteur magna duis quis. Quis laborum deserunt ipsum velit occaecat est laborum enim aute. Officia dolore sit voluptate quis mollit veniam. Laborum nisi ullamco nisi sit nulla cillum et id nisi.\n",
 							Gender: "male",
 						},
-						User{
+						client.User{
 							Id:     33,
 							Name:   "Twila Snow",
 							Age:    36,
 							About:  "Sint non sunt adipisicing sit laborum cillum magna nisi exercitation. Dolore officia esse dolore officia ea adipisicing amet ea nostrud elit cupidatat laboris. Proident culpa ullamco aute incididunt aute. Laboris et nulla incididunt consequat pariatur enim dolor incididunt adipisicing enim fugiat tempor ullamco. Amet est ullamco officia consectetur cupidatat non sunt laborum nisi in ex. Quis labore quis ipsum est nisi ex officia reprehenderit ad adipisicing fugiat. Labore fugiat ea dolore exercitation sint duis aliqua.\n",
 							Gender: "female",
 						},
-						User{
+						client.User{
 							Id:     18,
 							Name:   "Terrell Hall",
 							Age:    27,
@@ -454,34 +614,36 @@ func TestOrderField(t *testing.T) {
 						},
 					},
 					NextPage: true,
+					Total:    35,
+					Limit:    3,
 				},
 				nil,
 			},
 		},
 		{
-			Request: SearchRequest{
+			Request: client.SearchRequest{
 				Limit:      3,
 				OrderBy:    -1,
 				OrderField: "Name",
 			},
 			Result: Result{
-				&SearchResponse{
-					Users: []User{
-						User{
+				&client.SearchResponse{
+					Users: []client.User{
+						client.User{
 							Id:     13,
 							Name:   "Whitley Davidson",
 							Age:    40,
 							About:  "Consectetur dolore anim veniam aliqua deserunt officia eu. Et ullamco commodo ad officia duis ex incididunt proident consequat nostrud proident quis tempor. Sunt magna ad excepteur eu sint aliqua eiusmod deserunt proident. Do labore est dolore voluptate ullamco est dolore excepteur magna duis quis. Quis laborum deserunt ipsum velit occaecat est laborum enim aute. Officia dolore sit voluptate quis mollit veniam. Laborum nisi ullamco nisi sit nulla cillum et id nisi.\n",
 							Gender: "male",
 						},
-						User{
+						client.User{
 							Id:     33,
 							Name:   "Twila Snow",
 							Age:    36,
 							About:  "Sint non sunt adipisicing sit laborum cillum magna nisi exercitation. Dolore officia esse dolore officia ea adipisicing amet ea nostrud elit cupidatat laboris. Proident culpa ullamco aute incididunt aute. Laboris et nulla incididunt consequat pariatur enim dolor incididunt adipisicing enim fugiat tempor ullamco. Amet est ullamco officia consectetur cupidatat non sunt laborum nisi in ex. Quis labore quis ipsum est nisi ex officia reprehenderit ad adipisicing fugiat. Labore fugiat ea dolore exercitation sint duis aliqua.\n",
 							Gender: "female",
 						},
-						User{
+						client.User{
 							Id:     18,
 							Name:   "Terrell Hall",
 							Age:    27,
@@ -490,34 +652,36 @@ func TestOrderField(t *testing.T) {
 						},
 					},
 					NextPage: true,
+					Total:    35,
+					Limit:    3,
 				},
 				nil,
 			},
 		},
 		{
-			Request: SearchRequest{
+			Request: client.SearchRequest{
 				Limit:      3,
 				OrderBy:    -1,
 				OrderField: "Age",
 			},
 			Result: Result{
-				&SearchResponse{
-					Users: []User{
-						User{
+				&client.SearchResponse{
+					Users: []client.User{
+						client.User{
 							Id:     13,
 							Name:   "Whitley Davidson",
 							Age:    40,
 							About:  "Consectetur dolore anim veniam aliqua deserunt officia eu. Et ullamco commodo ad officia duis ex incididunt proident consequat nostrud proident quis tempor. Sunt magna ad excepteur eu sint aliqua eiusmod deserunt proident. Do labore est dolore voluptate ullamco est dolore excepteur magna duis quis. Quis laborum deserunt ipsum velit occaecat est laborum enim aute. Officia dolore sit voluptate quis mollit veniam. Laborum nisi ullamco nisi sit nulla cillum et id nisi.\n",
 							Gender: "male",
 						},
-						User{
+						client.User{
 							Id:     32,
 							Name:   "Christy Knapp",
 							Age:    40,
 							About:  "Incididunt culpa dolore laborum cupidatat consequat. Aliquip cupidatat pariatur sit consectetur laboris labore anim labore. Est sint ut ipsum dolor ipsum nisi tempor in tempor aliqua. Aliquip labore cillum est consequat anim officia non reprehenderit ex duis elit. Amet aliqua eu ad velit incididunt ad ut magna. Culpa dolore qui anim consequat commodo aute.\n",
 							Gender: "female",
 						},
-						User{
+						client.User{
 							Id:     6,
 							Name:   "Jennings Mays",
 							Age:    39,
@@ -526,28 +690,30 @@ func TestOrderField(t *testing.T) {
 						},
 					},
 					NextPage: true,
+					Total:    35,
+					Limit:    3,
 				},
 				nil,
 			},
 		},
 		{
-			Request: SearchRequest{
+			Request: client.SearchRequest{
 				Limit:      2,
 				Query:      "B",
 				OrderBy:    1,
 				OrderField: "Id",
 			},
 			Result: Result{
-				&SearchResponse{
-					Users: []User{
-						User{
+				&client.SearchResponse{
+					Users: []client.User{
+						client.User{
 							Id:     0,
 							Name:   "Boyd Wolf",
 							Age:    22,
 							About:  "Nulla cillum enim voluptate consequat laborum esse excepteur occaecat commodo nostrud excepteur ut cupidatat. Occaecat minim incididunt ut proident ad sint nostrud ad laborum sint pariatur. Ut nulla commodo dolore officia. Consequat anim eiusmod amet commodo eiusmod deserunt culpa. Ea sit dolore nostrud cillum proident nisi mollit est Lorem pariatur. Lorem aute officia deserunt dolor nisi aliqua consequat nulla nostrud ipsum irure id deserunt dolore. Minim reprehenderit nulla exercitation labore ipsum.\n",
 							Gender: "male",
 						},
-						User{
+						client.User{
 							Id:     2,
 							Name:   "Brooks Aguilar",
 							Age:    25,
@@ -556,21 +722,23 @@ func TestOrderField(t *testing.T) {
 						},
 					},
 					NextPage: true,
+					Total:    5,
+					Limit:    2,
 				},
 				nil,
 			},
 		},
 		{
-			Request: SearchRequest{
+			Request: client.SearchRequest{
 				Limit:      10,
 				Query:      "Boyd Wolf",
 				OrderBy:    1,
 				OrderField: "Id",
 			},
 			Result: Result{
-				&SearchResponse{
-					Users: []User{
-						User{
+				&client.SearchResponse{
+					Users: []client.User{
+						client.User{
 							Id:     0,
 							Name:   "Boyd Wolf",
 							Age:    22,
@@ -579,6 +747,8 @@ func TestOrderField(t *testing.T) {
 						},
 					},
 					NextPage: false,
+					Total:    1,
+					Limit:    10,
 				},
 				nil,
 			},
@@ -586,15 +756,23 @@ func TestOrderField(t *testing.T) {
 	}
 
 	for caseNum, testItem := range tests {
-		s := &SearchClient{
-			token,
-			ts.URL,
+		s := &client.SearchClient{
+			AccessToken: token,
+			URL:         ts.URL,
 		}
 		result, err := s.FindUsers(testItem.Request)
 
 		if err != nil {
 			t.Errorf("[%d] expected nil, got error: %#v", caseNum, err.Error())
 		}
+		if result == nil {
+			continue
+		}
+		if (result.NextCursor != "") != result.NextPage {
+			t.Errorf("[%d] NextCursor must be set only with NextPage, got %#v", caseNum, result.NextCursor)
+		}
+		// курсоры непрозрачны, а время каждый раз своё, сравниваем всё остальное
+		result.NextCursor, result.PrevCursor, result.Took = "", "", 0
 		if !reflect.DeepEqual(testItem.Result.Response, result) {
 			t.Errorf("[%d] wrong result, expected %#v, got %#v", caseNum, testItem.Result.Response, result)
 		}
@@ -613,19 +791,20 @@ func TestIoutilAndJSON(t *testing.T) {
 			result: "no such file or directory",
 		},
 		{
-			name:   "coverfile.html",
+			name:   "../coverfile.html",
 			result: "can't unpack result json",
 		},
 	}
 
 	for caseNum, testItem := range tests {
-		FileName = testItem.name
-		s := &SearchClient{
-			token,
-			ts.URL,
+		fileTs := httptest.NewServer(server.New(server.NewXMLFileStore(testItem.name), tokensFile, server.Options{}))
+		s := &client.SearchClient{
+			AccessToken: token,
+			URL:         fileTs.URL,
 		}
-		request := SearchRequest{}
+		request := client.SearchRequest{}
 		_, err := s.FindUsers(request)
+		fileTs.Close()
 
 		if err == nil {
 			t.Errorf("[%v] expected error, got nil", caseNum)
@@ -635,3 +814,72 @@ func TestIoutilAndJSON(t *testing.T) {
 		}
 	}
 }
+
+func TestPageMetadata(t *testing.T) {
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         ts.URL,
+	}
+	byID := []client.SortKey{{Field: "Id"}}
+	result, err := s.FindUsers(client.SearchRequest{Limit: 4, Offset: 8, Sort: byID, Filters: []client.Filter{{"age", client.FilterGte, "30"}}})
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	if result.Total != 22 || result.Offset != 8 || result.Limit != 4 || len(result.Users) != 4 || !result.NextPage {
+		t.Errorf("wrong result, got total %d, offset %d, limit %d, %d users", result.Total, result.Offset, result.Limit, len(result.Users))
+	}
+
+	// по курсору смещение считает сервер
+	next, err := s.FindUsers(client.SearchRequest{Limit: 4, Sort: byID, Filters: []client.Filter{{"age", client.FilterGte, "30"}}, Cursor: result.NextCursor})
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	if next.Total != 22 || next.Offset != 12 {
+		t.Errorf("wrong result, got total %d, offset %d", next.Total, next.Offset)
+	}
+}
+
+func TestLegacyResponse(t *testing.T) {
+	legacyTs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, ` [{"Id": 1, "Name": "Hilda Mayer"}, {"Id": 2, "Name": "Brooks Aguilar"}]`)
+	}))
+	defer legacyTs.Close()
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         legacyTs.URL,
+	}
+
+	result, err := s.FindUsers(client.SearchRequest{Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	expected := &client.SearchResponse{
+		Users:    []client.User{{Id: 1, Name: "Hilda Mayer"}},
+		NextPage: true,
+		Total:    -1,
+		Offset:   1,
+		Limit:    1,
+	}
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("wrong result, expected %#v, got %#v", expected, result)
+	}
+
+	// сервер отдаёт не больше limit записей из трёх: заполненная последняя страница - не повод
+	// ждать следующую
+	rows := []client.User{{Id: 1}, {Id: 2}, {Id: 3}}
+	limitTs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.FormValue("limit"))
+		if limit > len(rows) {
+			limit = len(rows)
+		}
+		json.NewEncoder(w).Encode(rows[:limit])
+	}))
+	defer limitTs.Close()
+	s.URL = limitTs.URL
+	for limit, nextPage := range map[int]bool{2: true, 3: false} {
+		result, err := s.FindUsers(client.SearchRequest{Limit: limit})
+		if err != nil || len(result.Users) != limit || result.NextPage != nextPage {
+			t.Errorf("limit %d: wrong result, expected %d users, NextPage %v, got %#v, %v", limit, limit, nextPage, result, err)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// виды ошибок FindUsers, проверяются через errors.Is. Подробности - в *SearchError
+var (
+	// ErrUnauthorized - сервер не принял AccessToken
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden - AccessToken принят, но у него нет доступа к этому методу
+	ErrForbidden = errors.New("forbidden")
+	// ErrTimeout - сервер не ответил за таймаут клиента
+	ErrTimeout = errors.New("timeout")
+	// ErrBadOrderField - сортировка по несуществующему полю
+	ErrBadOrderField = errors.New("bad order field")
+	// ErrBadRequest - сервер отверг запрос, его объяснение в SearchError.Message
+	ErrBadRequest = errors.New("bad request")
+	// ErrServerFatal - внутренняя ошибка сервера
+	ErrServerFatal = errors.New("server fatal error")
+	// ErrDecode - ответ сервера не разобрать
+	ErrDecode = errors.New("decode error")
+	// ErrRateLimited - квота токена исчерпана, когда можно повторить - в SearchError.RetryAfter
+	ErrRateLimited = errors.New("rate limited")
+	// ErrUnexpectedStatus - сервер ответил статусом, которого клиент не ждёт
+	ErrUnexpectedStatus = errors.New("unexpected status")
+)
+
+// SearchError - ошибка, полученная от сервера или при разборе его ответа
+type SearchError struct {
+	Kind       error  // один из Err* выше
+	StatusCode int    // HTTP статус ответа, 0 - если ответа не было
+	Body       []byte // тело ответа как есть
+	Message    string // текст ошибки от сервера, если он его прислал
+	Err        error  // исходная ошибка, например от encoding/json
+	// через сколько сервер просит повторить запрос (заголовок Retry-After), 0 - не просил
+	RetryAfter time.Duration
+
+	text string
+}
+
+func newSearchError(kind error, status int, body []byte, cause error, format string, args ...interface{}) *SearchError {
+	return &SearchError{Kind: kind, StatusCode: status, Body: body, Err: cause, text: fmt.Sprintf(format, args...)}
+}
+
+func (e *SearchError) Error() string {
+	if e.text == "" {
+		return e.Kind.Error()
+	}
+	return e.text
+}
+
+func (e *SearchError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// transportError - ответа от сервера нет совсем: соединение отвергнуто, имя не нашлось и т.п.
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return "unknown error " + e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+var (
+	// ErrCanceled - вызывающий отменил контекст запроса
+	ErrCanceled = errors.New("search canceled")
+	// ErrDeadlineExceeded - истёк срок, заданный контекстом запроса
+	ErrDeadlineExceeded = errors.New("search deadline exceeded")
+)
+
+// ContextError - запрос прерван контекстом вызывающего. errors.Is узнаёт в нём
+// и ErrCanceled или ErrDeadlineExceeded, и исходные context.Canceled или context.DeadlineExceeded
+type ContextError struct {
+	Kind error // ErrCanceled или ErrDeadlineExceeded
+	Err  error // ctx.Err()
+}
+
+func newContextError(err error) *ContextError {
+	kind := ErrCanceled
+	if errors.Is(err, context.DeadlineExceeded) {
+		kind = ErrDeadlineExceeded
+	}
+	return &ContextError{Kind: kind, Err: err}
+}
+
+func (e *ContextError) Error() string {
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+func (e *ContextError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
@@ -0,0 +1,30 @@
+package client
+
+import "time"
+
+// то, что нужно тестам из client_test, а снаружи не нужно
+
+var (
+	ParseRetryAfter = parseRetryAfter
+	NewContextError = newContextError
+)
+
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	return p.backoff(attempt)
+}
+
+func (b *CircuitBreaker) SetNow(now func() time.Time) {
+	b.now = now
+}
+
+func (b *CircuitBreaker) Allow() error {
+	return b.allow()
+}
+
+func (b *CircuitBreaker) Record(err error) {
+	b.record(err)
+}
+
+func (m *JWTMinter) SetNow(now func() time.Time) {
+	m.now = now
+}
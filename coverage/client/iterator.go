@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+)
+
+// IterateOptions - настройки SearchClient.Iterate
+type IterateOptions struct {
+	// сколько пользователей отдать всего, 0 - всех найденных
+	MaxItems int
+	// сколько страниц загружать заранее, сверх той, что сейчас читается; 0 - одну
+	Prefetch int
+}
+
+// UserIterator отдаёт найденных пользователей по одному, страницы подгружаются в фоне
+// по мере чтения. Использование как у sql.Rows:
+//
+//	it := client.Iterate(ctx, req, IterateOptions{})
+//	defer it.Close()
+//	for user, ok := it.Next(); ok; user, ok = it.Next() {
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type UserIterator struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	pages  chan []User
+	page   []User
+	closed bool
+	// загрузчик пишет сюда только до закрытия pages, остальное - после его завершения
+	err error
+}
+
+// Iterate обходит все страницы запроса. Limit задаёт размер страницы (по умолчанию MaxLimit),
+// Offset или Cursor - откуда начать. Дальше идём по курсорам, если сервер их отдаёт, иначе по Offset
+func (srv *SearchClient) Iterate(ctx context.Context, req SearchRequest, opts IterateOptions) *UserIterator {
+	if req.Limit <= 0 || req.Limit > MaxLimit {
+		req.Limit = MaxLimit
+	}
+	prefetch := opts.Prefetch
+	if prefetch <= 0 {
+		prefetch = 1
+	}
+
+	// ещё одну страницу, кроме буфера, загрузчик держит, пока ждёт места в нём
+	it := &UserIterator{pages: make(chan []User, prefetch-1)}
+	it.ctx, it.cancel = context.WithCancel(ctx)
+	go it.fetch(srv, req, opts.MaxItems)
+	return it
+}
+
+// fetch загружает страницы, пока они не кончатся, не наберётся maxItems или не отменят контекст.
+// Буфер pages и страница в ожидании отправки - это и есть Prefetch страниц вперёд
+func (it *UserIterator) fetch(srv *SearchClient, req SearchRequest, maxItems int) {
+	defer close(it.pages)
+	fetched := 0
+	for {
+		if maxItems > 0 && maxItems-fetched < req.Limit {
+			req.Limit = maxItems - fetched
+		}
+		// об отмене контекста сообщает Next, а после Close это и не ошибка
+		resp, err := srv.FindUsersContext(it.ctx, req)
+		if it.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			it.err = err
+			return
+		}
+
+		select {
+		case it.pages <- resp.Users:
+		case <-it.ctx.Done():
+			return
+		}
+		fetched += len(resp.Users)
+		if !resp.NextPage || len(resp.Users) == 0 || maxItems > 0 && fetched >= maxItems {
+			return
+		}
+
+		if resp.NextCursor != "" {
+			req.Cursor = resp.NextCursor
+		} else {
+			req.Offset += len(resp.Users)
+		}
+	}
+}
+
+// Next возвращает следующего пользователя; false - пользователи кончились,
+// причину ошибки, если она была, вернёт Err
+func (it *UserIterator) Next() (User, bool) {
+	for len(it.page) == 0 {
+		page, ok := <-it.pages
+		if !ok {
+			if it.err == nil && !it.closed && it.ctx.Err() != nil {
+				it.err = newContextError(it.ctx.Err())
+			}
+			return User{}, false
+		}
+		it.page = page
+	}
+	if err := it.ctx.Err(); err != nil {
+		// уже загруженные страницы после отмены не отдаём
+		it.Close()
+		if it.err == nil {
+			it.err = newContextError(err)
+		}
+		return User{}, false
+	}
+
+	user := it.page[0]
+	it.page = it.page[1:]
+	return user, true
+}
+
+// Err - ошибка, из-за которой обход закончился раньше времени. Отмена контекста - *ContextError,
+// как у FindUsersContext. Остановка через Close ошибкой не считается
+func (it *UserIterator) Err() error {
+	return it.err
+}
+
+// Close останавливает загрузку и дожидается её завершения. После Close Next возвращает false
+func (it *UserIterator) Close() {
+	it.closed = true
+	it.cancel()
+	for range it.pages {
+	}
+	it.page = nil
+}
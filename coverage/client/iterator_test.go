@@ -0,0 +1,133 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"coverage/client"
+)
+
+// countingServer - SearchServer, который считает запросы
+func countingServer(requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		searchServer.ServeHTTP(w, r)
+	}))
+}
+
+func TestIterate(t *testing.T) {
+	var requests int32
+	countingTs := countingServer(&requests)
+	defer countingTs.Close()
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         countingTs.URL,
+	}
+
+	tests := []struct {
+		request  client.SearchRequest
+		opts     client.IterateOptions
+		count    int
+		requests int32
+	}{
+		{client.SearchRequest{Limit: 4}, client.IterateOptions{}, 35, 9},
+		{client.SearchRequest{}, client.IterateOptions{}, 35, 2},
+		{client.SearchRequest{Limit: 4}, client.IterateOptions{MaxItems: 10}, 10, 3},
+		{client.SearchRequest{Limit: 4, Offset: 30}, client.IterateOptions{Prefetch: 3}, 5, 2},
+		{client.SearchRequest{Limit: 4, Query: "Boyd"}, client.IterateOptions{}, 1, 1},
+	}
+	for caseNum, testItem := range tests {
+		atomic.StoreInt32(&requests, 0)
+		it := s.Iterate(context.Background(), testItem.request, testItem.opts)
+		var ids []int
+		for user, ok := it.Next(); ok; user, ok = it.Next() {
+			ids = append(ids, user.Id)
+		}
+		it.Close()
+		if it.Err() != nil {
+			t.Errorf("[%d] expected nil, got error: %v", caseNum, it.Err())
+			continue
+		}
+
+		if len(ids) != testItem.count || atomic.LoadInt32(&requests) != testItem.requests {
+			t.Errorf("[%d] wrong result, expected %d users in %d requests, got %d in %d",
+				caseNum, testItem.count, testItem.requests, len(ids), requests)
+		}
+		for i := range ids {
+			if ids[i] != ids[0]+i {
+				t.Errorf("[%d] wrong order: %v", caseNum, ids)
+				break
+			}
+		}
+	}
+}
+
+func TestIteratePrefetch(t *testing.T) {
+	var requests int32
+	countingTs := countingServer(&requests)
+	defer countingTs.Close()
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         countingTs.URL,
+	}
+
+	it := s.Iterate(context.Background(), client.SearchRequest{Limit: 2}, client.IterateOptions{Prefetch: 2})
+	// одна страница читается, ещё две загружены заранее, дальше загрузчик ждёт
+	user, _ := it.Next()
+	time.Sleep(100 * time.Millisecond)
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Errorf("wrong result, expected 2 pages ahead of reader, got %d requests", n)
+	}
+
+	ids := []int{user.Id}
+	for user, ok := it.Next(); ok && len(ids) < 5; user, ok = it.Next() {
+		ids = append(ids, user.Id)
+	}
+	it.Close()
+	if !reflect.DeepEqual(ids, []int{0, 1, 2, 3, 4}) || it.Err() != nil {
+		t.Errorf("wrong result, got %v, %v", ids, it.Err())
+	}
+	if _, ok := it.Next(); ok {
+		t.Errorf("expected no users after Close")
+	}
+}
+
+func TestIterateCancel(t *testing.T) {
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         ts.URL,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	it := s.Iterate(ctx, client.SearchRequest{Limit: 4}, client.IterateOptions{})
+	defer it.Close()
+
+	if _, ok := it.Next(); !ok {
+		t.Fatalf("expected user, got %v", it.Err())
+	}
+	cancel()
+	if _, ok := it.Next(); ok {
+		t.Errorf("expected no users after cancel")
+	}
+	var ctxErr *client.ContextError
+	if !errors.As(it.Err(), &ctxErr) || !errors.Is(it.Err(), client.ErrCanceled) || !errors.Is(it.Err(), context.Canceled) {
+		t.Errorf("wrong result, expected ContextError for %v, got %#v", context.Canceled, it.Err())
+	}
+}
+
+func TestIterateError(t *testing.T) {
+	s := &client.SearchClient{
+		AccessToken: "bad",
+		URL:         ts.URL,
+	}
+	it := s.Iterate(context.Background(), client.SearchRequest{}, client.IterateOptions{})
+	defer it.Close()
+	if _, ok := it.Next(); ok || !errors.Is(it.Err(), client.ErrUnauthorized) {
+		t.Errorf("wrong result, expected %v, got %v", client.ErrUnauthorized, it.Err())
+	}
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy - как SearchClient повторяет запрос после временных сбоев: таймаута,
+// ответа 5xx или 429 или оборванного соединения. Остальные ошибки не повторяются
+type RetryPolicy struct {
+	// всего попыток вместе с первой; 0 и 1 - без повторов
+	MaxAttempts int
+	// пауза перед второй попыткой, перед каждой следующей вдвое больше
+	BaseDelay time.Duration
+	// потолок паузы; если Retry-After просит ждать дольше, повторов больше не будет. 0 - без потолка
+	MaxDelay time.Duration
+	// какая доля паузы случайна: 0 - пауза ровно по расписанию, 1 - от нуля до полной
+	Jitter float64
+}
+
+// DefaultRetryPolicy - разумные настройки для SearchClient.Retry
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+	Jitter:      0.5,
+}
+
+// backoff - пауза перед попыткой attempt+1 по расписанию, без учёта Retry-After
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay == 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		spread := time.Duration(float64(delay) * p.Jitter)
+		delay -= time.Duration(rand.Int63n(int64(spread) + 1))
+	}
+	return delay
+}
+
+// retryable - временный ли сбой, после которого запрос можно просто повторить
+func retryable(err error) bool {
+	if errors.Is(err, ErrTimeout) || errors.Is(err, ErrServerFatal) || errors.Is(err, ErrRateLimited) {
+		return true
+	}
+	// соединение оборвалось, а ответа так и не было
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// withRetry выполняет attempt, пока он не пройдёт, не кончатся попытки или ошибка не окажется постоянной
+func (srv *SearchClient) withRetry(ctx context.Context, attempt func() (*SearchResponse, error)) (*SearchResponse, error) {
+	for n := 1; ; n++ {
+		resp, err := attempt()
+		if err == nil || srv.Retry == nil || n >= srv.Retry.MaxAttempts || !retryable(err) {
+			return resp, err
+		}
+
+		delay := srv.Retry.backoff(n)
+		var searchErr *SearchError
+		if errors.As(err, &searchErr) && searchErr.RetryAfter > delay {
+			delay = searchErr.RetryAfter
+			if srv.Retry.MaxDelay > 0 && delay > srv.Retry.MaxDelay {
+				return resp, err
+			}
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, newContextError(ctx.Err())
+		}
+	}
+}
+
+// parseRetryAfter понимает оба вида Retry-After: число секунд и HTTP-дату
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,181 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"coverage/client"
+)
+
+// flakyServer первые failures запросов обрабатывает fail, остальные - SearchServer
+func flakyServer(failures int32, requests *int32, fail http.HandlerFunc) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(requests, 1) <= failures {
+			fail(w, r)
+			return
+		}
+		searchServer.ServeHTTP(w, r)
+	}))
+}
+
+func TestRetry(t *testing.T) {
+	policy := &client.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	internalError := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	tests := []struct {
+		failures int32
+		fail     http.HandlerFunc
+		request  client.SearchRequest
+		kind     error // nil - в итоге успех
+		requests int32
+	}{
+		{2, internalError, client.SearchRequest{}, nil, 3},
+		{3, internalError, client.SearchRequest{}, client.ErrServerFatal, 3},
+		{
+			1,
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			client.SearchRequest{}, nil, 2,
+		},
+		{
+			1,
+			func(w http.ResponseWriter, r *http.Request) {
+				// обрываем соединение, не ответив
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+			},
+			client.SearchRequest{}, nil, 2,
+		},
+		{
+			1,
+			func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(100 * time.Millisecond)
+			},
+			client.SearchRequest{}, nil, 2,
+		},
+		{
+			1,
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			client.SearchRequest{}, nil, 2,
+		},
+		// постоянные ошибки не повторяются
+		{0, nil, client.SearchRequest{OrderBy: 1, OrderField: "N"}, client.ErrBadOrderField, 1},
+	}
+
+	for caseNum, testItem := range tests {
+		var requests int32
+		flakyTs := flakyServer(testItem.failures, &requests, testItem.fail)
+		s := &client.SearchClient{
+			AccessToken: token,
+			URL:         flakyTs.URL,
+			Timeout:     50 * time.Millisecond,
+			Retry:       policy,
+		}
+		_, err := s.FindUsers(testItem.request)
+		flakyTs.Close()
+
+		if testItem.kind == nil && err != nil || !errors.Is(err, testItem.kind) {
+			t.Errorf("[%d] wrong result, expected %v, got %v", caseNum, testItem.kind, err)
+		}
+		if atomic.LoadInt32(&requests) != testItem.requests {
+			t.Errorf("[%d] wrong result, expected %d requests, got %d", caseNum, testItem.requests, requests)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	unavailable := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	var requests int32
+	flakyTs := flakyServer(1, &requests, unavailable)
+	defer flakyTs.Close()
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         flakyTs.URL,
+		Retry:       &client.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Second},
+	}
+	began := time.Now()
+	if _, err := s.FindUsers(client.SearchRequest{}); err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	if took := time.Since(began); took < time.Second {
+		t.Errorf("wrong result, expected to wait for Retry-After, retried after %v", took)
+	}
+
+	// ждать дольше MaxDelay не будем
+	atomic.StoreInt32(&requests, 0)
+	s.Retry = &client.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 100 * time.Millisecond}
+	_, err := s.FindUsers(client.SearchRequest{})
+	var searchErr *client.SearchError
+	if !errors.As(err, &searchErr) || searchErr.RetryAfter != time.Second || requests != 1 {
+		t.Errorf("wrong result, expected Retry-After error after 1 request, got %v after %d", err, requests)
+	}
+}
+
+func TestRetryCancel(t *testing.T) {
+	var requests int32
+	flakyTs := flakyServer(100, &requests, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	defer flakyTs.Close()
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         flakyTs.URL,
+		Retry:       &client.RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := s.FindUsersContext(ctx, client.SearchRequest{})
+	if !errors.Is(err, client.ErrDeadlineExceeded) || requests != 1 {
+		t.Errorf("wrong result, expected deadline error after 1 request, got %v after %d", err, requests)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := client.RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	expected := []time.Duration{10, 20, 40, 50, 50}
+	for i, delay := range expected {
+		if got := policy.Backoff(i + 1); got != delay*time.Millisecond {
+			t.Errorf("[%d] wrong result, expected %v, got %v", i, delay*time.Millisecond, got)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := policy.Backoff(2); got < 10*time.Millisecond || got > 20*time.Millisecond {
+			t.Fatalf("wrong result, expected between 10ms and 20ms, got %v", got)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"3":                             3 * time.Second,
+		"-1":                            0,
+		"soon":                          0,
+		"Wed, 01 Jan 2020 00:00:10 GMT": 10 * time.Second,
+		"Tue, 31 Dec 2019 23:59:00 GMT": 0,
+	}
+	for header, expected := range tests {
+		if got := client.ParseRetryAfter(header, now); got != expected {
+			t.Errorf("%q: wrong result, expected %v, got %v", header, expected, got)
+		}
+	}
+}
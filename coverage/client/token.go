@@ -0,0 +1,168 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// области доступа токенов
+const (
+	// ScopeSearch - поиск пользователей
+	ScopeSearch = "search"
+	// ScopeAdmin - служебные ручки вроде перезагрузки данных
+	ScopeAdmin = "admin"
+)
+
+// JWTAlgorithm - единственный алгоритм подписи, который понимает сервер
+const JWTAlgorithm = "HS256"
+
+// JWTClaims - поля JWT, которые понимает сервер. Время - unix-секунды
+type JWTClaims struct {
+	Issuer    string   `json:"iss,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	// области доступа через пробел, как в OAuth: "search admin"
+	Scope string `json:"scope,omitempty"`
+}
+
+// Audience - aud бывает и строкой, и массивом строк
+type Audience []string
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(a))
+}
+
+// Contains - есть ли aud среди получателей токена
+func (a Audience) Contains(aud string) bool {
+	for _, s := range a {
+		if s == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// JWTHeader - заголовок JWT
+type JWTHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+}
+
+// JWTSignature - HMAC-SHA256 от "заголовок.данные"
+func JWTSignature(signingInput string, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+	return mac.Sum(nil)
+}
+
+// SignJWT собирает и подписывает токен
+func SignJWT(keyID string, secret []byte, claims *JWTClaims) (string, error) {
+	header, err := json.Marshal(JWTHeader{Algorithm: JWTAlgorithm, Type: "JWT", KeyID: keyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(JWTSignature(signingInput, secret)), nil
+}
+
+// TokenSource выдаёт AccessToken для очередного запроса SearchClient
+type TokenSource interface {
+	AccessToken() (string, error)
+}
+
+const defaultJWTTTL = 15 * time.Minute
+
+// JWTMinter выпускает JWT для SearchClient.TokenSource и сам выпускает новый,
+// когда до истечения текущего остаётся меньше RefreshBefore
+type JWTMinter struct {
+	KeyID    string
+	Secret   []byte
+	Issuer   string
+	Audience string
+	Subject  string
+	Scopes   []string
+	// срок жизни токена, по умолчанию defaultJWTTTL
+	TTL time.Duration
+	// по умолчанию десятая часть TTL
+	RefreshBefore time.Duration
+
+	now       func() time.Time
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// Mint выпускает новый токен, действующий с now
+func (m *JWTMinter) Mint(now time.Time) (string, time.Time, error) {
+	expiresAt := now.Add(m.ttl())
+	claims := &JWTClaims{
+		Issuer:    m.Issuer,
+		Subject:   m.Subject,
+		ExpiresAt: expiresAt.Unix(),
+		NotBefore: now.Unix(),
+		IssuedAt:  now.Unix(),
+		Scope:     strings.Join(m.Scopes, " "),
+	}
+	if m.Audience != "" {
+		claims.Audience = Audience{m.Audience}
+	}
+	token, err := SignJWT(m.KeyID, m.Secret, claims)
+	return token, expiresAt, err
+}
+
+func (m *JWTMinter) ttl() time.Duration {
+	if m.TTL <= 0 {
+		return defaultJWTTTL
+	}
+	return m.TTL
+}
+
+// AccessToken отдаёт текущий токен или выпускает новый, если срок текущего подходит к концу
+func (m *JWTMinter) AccessToken() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if m.now != nil {
+		now = m.now()
+	}
+	refreshBefore := m.RefreshBefore
+	if refreshBefore <= 0 {
+		refreshBefore = m.ttl() / 10
+	}
+	if m.token != "" && now.Add(refreshBefore).Before(m.expiresAt) {
+		return m.token, nil
+	}
+
+	token, expiresAt, err := m.Mint(now)
+	if err != nil {
+		return "", err
+	}
+	m.token, m.expiresAt = token, expiresAt
+	return token, nil
+}
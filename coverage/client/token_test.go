@@ -0,0 +1,54 @@
+package client_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"coverage/client"
+)
+
+const (
+	oldJWTSecret = "old-secret-kept-until-tokens-expire"
+	newJWTSecret = "new-secret-used-for-all-new-tokens"
+)
+
+func TestJWTMinterRefresh(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	minter := &client.JWTMinter{KeyID: "2020", Secret: []byte(newJWTSecret), TTL: 10 * time.Minute, RefreshBefore: time.Minute}
+	minter.SetNow(func() time.Time { return now })
+
+	first, _ := minter.AccessToken()
+	now = now.Add(8 * time.Minute)
+	if second, _ := minter.AccessToken(); second != first {
+		t.Errorf("wrong result, expected the same token before RefreshBefore")
+	}
+	now = now.Add(90 * time.Second)
+	if third, _ := minter.AccessToken(); third == first {
+		t.Errorf("wrong result, expected a new token within RefreshBefore of expiry")
+	}
+}
+
+func TestClientTokenSource(t *testing.T) {
+	s := &client.SearchClient{
+		URL: ts.URL,
+		TokenSource: &client.JWTMinter{
+			KeyID:    "2020",
+			Secret:   []byte(newJWTSecret),
+			Issuer:   "gateway",
+			Audience: "search",
+			Scopes:   []string{client.ScopeSearch},
+		},
+	}
+	if _, err := s.FindUsers(client.SearchRequest{Limit: 1}); err != nil {
+		t.Errorf("expected nil, got error: %v", err)
+	}
+
+	s.TokenSource = &client.JWTMinter{KeyID: "2020", Secret: []byte(oldJWTSecret), Issuer: "gateway", Audience: "search"}
+	_, err := s.FindUsers(client.SearchRequest{Limit: 1})
+	var searchErr *client.SearchError
+	if !errors.As(err, &searchErr) || searchErr.Kind != client.ErrUnauthorized || !strings.Contains(searchErr.Message, "bad signature") {
+		t.Errorf("wrong result, expected bad signature, got %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"coverage/server"
+)
+
+// config - настройки запуска. Каждый флаг можно задать и переменной окружения,
+// флаг важнее
+type config struct {
+	Addr            string
+	DatasetFile     string
+	TokensFile      string
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	ShutdownTimeout time.Duration
+	ReloadInterval  time.Duration
+	CursorSecret    string
+	TLSCert         string
+	TLSKey          string
+}
+
+// parseConfig разбирает флаги, значения по умолчанию берутся из окружения
+func parseConfig(args []string, getenv func(string) string) (*config, error) {
+	env := func(name, fallback string) string {
+		if value := getenv(name); value != "" {
+			return value
+		}
+		return fallback
+	}
+	var envErr error
+	envDuration := func(name string, fallback time.Duration) time.Duration {
+		value := getenv(name)
+		if value == "" {
+			return fallback
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil && envErr == nil {
+			envErr = fmt.Errorf("%s: %v", name, err)
+		}
+		return d
+	}
+
+	c := &config{}
+	fs := flag.NewFlagSet("searchserver", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.StringVar(&c.Addr, "addr", env("SEARCH_ADDR", ":8080"), "адрес, на котором слушать (SEARCH_ADDR)")
+	fs.StringVar(&c.DatasetFile, "dataset", env("SEARCH_DATASET", "dataset.xml"), "файл с пользователями: xml, json, ndjson или csv (SEARCH_DATASET)")
+	fs.StringVar(&c.TokensFile, "tokens", env("SEARCH_TOKENS", ""), "файл с токенами доступа, обязателен (SEARCH_TOKENS)")
+	fs.DurationVar(&c.ReadTimeout, "read-timeout", envDuration("SEARCH_READ_TIMEOUT", 5*time.Second), "сколько ждать запрос целиком (SEARCH_READ_TIMEOUT)")
+	fs.DurationVar(&c.WriteTimeout, "write-timeout", envDuration("SEARCH_WRITE_TIMEOUT", 10*time.Second), "сколько отдавать ответ (SEARCH_WRITE_TIMEOUT)")
+	fs.DurationVar(&c.ShutdownTimeout, "shutdown-timeout", envDuration("SEARCH_SHUTDOWN_TIMEOUT", 15*time.Second), "сколько ждать начатые запросы при остановке (SEARCH_SHUTDOWN_TIMEOUT)")
+	fs.DurationVar(&c.ReloadInterval, "reload-interval", envDuration("SEARCH_RELOAD_INTERVAL", server.DefaultReloadInterval), "как часто проверять файл с пользователями, 0 - никогда (SEARCH_RELOAD_INTERVAL)")
+	fs.StringVar(&c.CursorSecret, "cursor-secret", env("SEARCH_CURSOR_SECRET", ""), "ключ подписи курсоров, общий для всех копий сервера; пустой - случайный (SEARCH_CURSOR_SECRET)")
+	fs.StringVar(&c.TLSCert, "tls-cert", env("SEARCH_TLS_CERT", ""), "сертификат для HTTPS (SEARCH_TLS_CERT)")
+	fs.StringVar(&c.TLSKey, "tls-key", env("SEARCH_TLS_KEY", ""), "ключ сертификата (SEARCH_TLS_KEY)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if envErr != nil {
+		return nil, envErr
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+	// умолчания нет намеренно: без своего файла сервер открылся бы токенами из тестов
+	if c.TokensFile == "" {
+		return nil, errors.New("tokens file is required: set -tokens or SEARCH_TOKENS")
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return nil, errors.New("tls-cert and tls-key must be set together")
+	}
+	return c, nil
+}
+
+// httpServer проверяет, что данные и токены читаются, и собирает http.Server.
+// Лучше не стартовать вовсе, чем отвечать ошибкой на каждый запрос
+func httpServer(c *config) (*http.Server, error) {
+	store := server.NewFileStore(c.DatasetFile, server.LoadDatasetProgress(logProgress), server.FileOptions{ReloadInterval: c.ReloadInterval})
+	if _, err := store.Count(); err != nil {
+		return nil, fmt.Errorf("dataset: %v", err)
+	}
+	if _, err := server.LoadTokens(c.TokensFile); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("tokens: %v", err)
+	}
+	handler := server.New(store, c.TokensFile, server.Options{CursorSecret: []byte(c.CursorSecret)})
+	srv := &http.Server{
+		Addr:         c.Addr,
+		Handler:      handler,
+		ReadTimeout:  c.ReadTimeout,
+		WriteTimeout: c.WriteTimeout,
+	}
+	// после остановки сервера за файлами следить незачем
+	srv.RegisterOnShutdown(func() {
+		handler.Close()
+		store.Close()
+	})
+	return srv, nil
+}
+
+// logProgress пишет в лог, сколько большого XML уже загружено
+func logProgress(p server.LoadProgress) {
+	if p.Total > 0 {
+		log.Printf("dataset: %d rows, %d%%", p.Rows, p.Bytes*100/p.Total)
+		return
+	}
+	log.Printf("dataset: %d rows, %d bytes", p.Rows, p.Bytes)
+}
+
+// serve обслуживает ln, пока не отменят ctx, а потом ждёт начатые запросы
+// не дольше c.ShutdownTimeout
+func serve(ctx context.Context, srv *http.Server, ln net.Listener, c *config) error {
+	errs := make(chan error, 1)
+	go func() {
+		if c.TLSCert != "" {
+			errs <- srv.ServeTLS(ln, c.TLSCert, c.TLSKey)
+		} else {
+			errs <- srv.Serve(ln)
+		}
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), c.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown: %v", err)
+	}
+	if err := <-errs; err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func main() {
+	c, err := parseConfig(os.Args[1:], os.Getenv)
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	srv, err := httpServer(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", c.Addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	log.Printf("listening on %s, dataset %s", ln.Addr(), c.DatasetFile)
+	if err := serve(ctx, srv, ln, c); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("stopped")
+}
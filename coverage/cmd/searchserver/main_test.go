@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseConfig(t *testing.T) {
+	env := map[string]string{
+		"SEARCH_ADDR":          ":9090",
+		"SEARCH_DATASET":       "users.xml",
+		"SEARCH_READ_TIMEOUT":  "2s",
+		"SEARCH_TOKENS":        "tokens.json",
+		"SEARCH_CURSOR_SECRET": "cursors",
+	}
+	c, err := parseConfig([]string{"-addr", "127.0.0.1:7070", "-write-timeout", "3s"}, func(name string) string { return env[name] })
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	// флаг важнее переменной окружения, без флага берётся окружение, без обоих - умолчание
+	if c.Addr != "127.0.0.1:7070" || c.DatasetFile != "users.xml" || c.TokensFile != "tokens.json" || c.CursorSecret != "cursors" ||
+		c.ReadTimeout != 2*time.Second || c.WriteTimeout != 3*time.Second {
+		t.Errorf("wrong result, got %+v", c)
+	}
+
+	bad := []struct {
+		args []string
+		env  map[string]string
+	}{
+		{[]string{"-tls-cert", "cert.pem"}, nil},
+		{nil, map[string]string{"SEARCH_TLS_KEY": "key.pem"}},
+		{nil, map[string]string{"SEARCH_READ_TIMEOUT": "soon"}},
+		{[]string{"-read-timeout", "soon"}, nil},
+		{[]string{"extra"}, nil},
+		{nil, map[string]string{"SEARCH_TOKENS": ""}},
+	}
+	for caseNum, testItem := range bad {
+		getenv := func(name string) string {
+			if value, ok := testItem.env[name]; ok {
+				return value
+			}
+			return env[name]
+		}
+		if _, err := parseConfig(testItem.args, getenv); err == nil {
+			t.Errorf("[%d] expected error, got nil", caseNum)
+		}
+	}
+}
+
+func TestHTTPServerValidates(t *testing.T) {
+	if _, err := httpServer(&config{DatasetFile: "missing.xml", TokensFile: "../../testdata/tokens.json"}); err == nil {
+		t.Errorf("expected error for missing dataset, got nil")
+	}
+	if _, err := httpServer(&config{DatasetFile: "../../dataset.xml", TokensFile: "missing.json"}); err == nil {
+		t.Errorf("expected error for missing tokens, got nil")
+	}
+}
+
+func TestServeDrains(t *testing.T) {
+	started := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("done"))
+	})}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, srv, ln, &config{ShutdownTimeout: time.Second})
+	}()
+
+	responses := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			t.Errorf("expected nil, got error: %v", err)
+		}
+		responses <- resp
+	}()
+
+	// останавливаем сервер посреди запроса: запрос должен успеть завершиться
+	<-started
+	cancel()
+	if err := <-served; err != nil {
+		t.Errorf("expected nil, got error: %v", err)
+	}
+	if resp := <-responses; resp == nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("wrong result, expected in-flight request to finish, got %v", resp)
+	} else {
+		resp.Body.Close()
+	}
+
+	if _, err := http.Get("http://" + ln.Addr().String()); err == nil {
+		t.Errorf("expected error after shutdown, got nil")
+	}
+}
@@ -0,0 +1,286 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"coverage/client"
+)
+
+var knownScopes = map[string]bool{client.ScopeSearch: true, client.ScopeAdmin: true}
+
+var (
+	errTokenInvalid = errors.New("bad AccessToken")
+	errTokenExpired = errors.New("AccessToken expired")
+	errTokenScope   = errors.New("AccessToken has no access to this method")
+)
+
+// Token - токен из файла токенов
+type Token struct {
+	Name   string   `json:"name"`
+	Token  string   `json:"token"`
+	Scopes []string `json:"scopes"`
+	// после этого момента токен не действует; пустое - бессрочный
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+	// своя квота запросов; nil - общая квота файла токенов
+	RateLimit *RateLimit `json:"rate_limit,omitempty"`
+}
+
+// HasScope - есть ли у токена область доступа scope
+func (t *Token) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenStore - токены, которым разрешён доступ
+type TokenStore struct {
+	tokens []Token
+	hashes [][sha256.Size]byte
+	jwt    *JWTVerifier // nil - JWT не принимаются
+	// квота токенов без своей, в том числе всех JWT; nil - без ограничений
+	rateLimit *RateLimit
+}
+
+// NewTokenStore проверяет токены: непустые, без повторов, с известными областями доступа
+func NewTokenStore(tokens []Token) (*TokenStore, error) {
+	s := &TokenStore{tokens: tokens}
+	seen := map[[sha256.Size]byte]bool{}
+	for _, t := range tokens {
+		if t.Token == "" {
+			return nil, fmt.Errorf("token %q: empty token", t.Name)
+		}
+		for _, scope := range t.Scopes {
+			if !knownScopes[scope] {
+				return nil, fmt.Errorf("token %q: unknown scope %q", t.Name, scope)
+			}
+		}
+		if err := t.RateLimit.validate(); err != nil {
+			return nil, fmt.Errorf("token %q: %v", t.Name, err)
+		}
+		hash := sha256.Sum256([]byte(t.Token))
+		if seen[hash] {
+			return nil, fmt.Errorf("token %q: duplicate token", t.Name)
+		}
+		seen[hash] = true
+		s.hashes = append(s.hashes, hash)
+	}
+	return s, nil
+}
+
+// SetJWT разрешает вход по JWT, проверенным v
+func (s *TokenStore) SetJWT(v *JWTVerifier) {
+	s.jwt = v
+}
+
+// SetRateLimit задаёт квоту для токенов, у которых нет своей
+func (s *TokenStore) SetRateLimit(limit *RateLimit) error {
+	if err := limit.validate(); err != nil {
+		return err
+	}
+	s.rateLimit = limit
+	return nil
+}
+
+// rateLimitFor - квота токена и ключ, по которому она считается. Для JWT ключ - sub,
+// иначе каждый перевыпущенный токен получал бы новую квоту
+func (s *TokenStore) rateLimitFor(t *Token) (string, *RateLimit) {
+	key := "token:" + t.Token
+	if t.Token == "" {
+		key = "jwt:" + t.Name
+	}
+	if t.RateLimit != nil {
+		return key, t.RateLimit
+	}
+	return key, s.rateLimit
+}
+
+// LoadTokens читает файл токенов. Секции jwt и rate_limit необязательны, см. JWTConfig и RateLimit:
+//
+//	{
+//	  "tokens": [{"name": "ui", "token": "...", "scopes": ["search"], "expires_at": "2030-01-01T00:00:00Z",
+//	              "rate_limit": {"rate": 50, "burst": 100}}],
+//	  "jwt": {"issuer": "gateway", "audience": "search", "keys": [{"kid": "2024-01", "secret": "..."}]},
+//	  "rate_limit": {"rate": 10, "burst": 20}
+//	}
+func LoadTokens(fileName string) (*TokenStore, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	config := struct {
+		Tokens    []Token    `json:"tokens"`
+		JWT       *JWTConfig `json:"jwt"`
+		RateLimit *RateLimit `json:"rate_limit"`
+	}{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+	store, err := NewTokenStore(config.Tokens)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+	if config.JWT != nil {
+		verifier, err := NewJWTVerifier(*config.JWT)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", fileName, err)
+		}
+		store.SetJWT(verifier)
+	}
+	if err := store.SetRateLimit(config.RateLimit); err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+	return store, nil
+}
+
+// Authenticate ищет токен и проверяет срок и область доступа.
+// Сравниваются хэши за постоянное время, и перебираются все токены, чтобы по времени
+// ответа нельзя было подбирать токен. JWT проверяются подписью, если они разрешены
+func (s *TokenStore) Authenticate(value, scope string, now time.Time) (*Token, error) {
+	if s.jwt != nil && looksLikeJWT(value) {
+		return s.authenticateJWT(value, scope, now)
+	}
+
+	hash := sha256.Sum256([]byte(value))
+	var found *Token
+	for i := range s.hashes {
+		if subtle.ConstantTimeCompare(hash[:], s.hashes[i][:]) == 1 {
+			found = &s.tokens[i]
+		}
+	}
+
+	switch {
+	case found == nil || value == "":
+		return nil, errTokenInvalid
+	case !found.ExpiresAt.IsZero() && !now.Before(found.ExpiresAt):
+		return nil, errTokenExpired
+	case !found.HasScope(scope):
+		return nil, errTokenScope
+	}
+	return found, nil
+}
+
+func (s *TokenStore) authenticateJWT(value, scope string, now time.Time) (*Token, error) {
+	claims, err := s.jwt.Verify(value, now)
+	if err != nil {
+		return nil, err
+	}
+	token := &Token{
+		Name:      claims.Subject,
+		Scopes:    strings.Fields(claims.Scope),
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+	}
+	if !token.HasScope(scope) {
+		return nil, errTokenScope
+	}
+	return token, nil
+}
+
+// tokenCache - токены сервера из файла fileName. Запросы берут готовую версию без блокировок
+// и обращений к диску, файл перечитывается в фоне, см. watch
+type tokenCache struct {
+	fileName string
+	tokens   atomic.Value // *TokenStore
+
+	mu      sync.Mutex // защищает modTime и не даёт двум reload идти параллельно
+	modTime time.Time
+}
+
+// get отдаёт текущую версию токенов. Пока файл ни разу не прочитался, пробует прочитать его сразу,
+// чтобы сервер заработал, как только файл появится
+func (c *tokenCache) get() (*TokenStore, error) {
+	if store, ok := c.tokens.Load().(*TokenStore); ok {
+		return store, nil
+	}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c.tokens.Load().(*TokenStore), nil
+}
+
+// reload перечитывает файл, если у него поменялся mtime.
+// Если новая версия битая, продолжаем с предыдущей
+func (c *tokenCache) reload() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, loaded := c.tokens.Load().(*TokenStore)
+	info, err := os.Stat(c.fileName)
+	if err != nil {
+		return err
+	}
+	if loaded && info.ModTime().Equal(c.modTime) {
+		return nil
+	}
+
+	store, err := LoadTokens(c.fileName)
+	// запоминаем mtime и при ошибке, чтобы не разбирать тот же битый файл на каждой проверке
+	c.modTime = info.ModTime()
+	if err != nil {
+		return err
+	}
+	c.tokens.Store(store)
+	return nil
+}
+
+// watch раз в interval вызывает reload, пока не вызовут stop. Ошибки только пишутся в лог:
+// запросы продолжают работать с прежними токенами
+func (c *tokenCache) watch(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.reload(); err != nil {
+					log.Printf("reload %s: %v, keep previous tokens", c.fileName, err)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// authorize проверяет AccessToken запроса на область доступа scope и его квоту.
+// Если доступа нет, пишет ответ с ошибкой и возвращает false
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request, scope string) bool {
+	store, err := s.tokens.get()
+	if err != nil {
+		log.Printf("load tokens: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, "can't load tokens")
+		return false
+	}
+
+	token, err := store.Authenticate(r.Header.Get("AccessToken"), scope, time.Now())
+	switch {
+	case err == nil:
+		key, limit := store.rateLimitFor(token)
+		return s.limitRate(w, key, limit)
+	case errors.Is(err, errTokenScope):
+		w.WriteHeader(http.StatusForbidden)
+	default:
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+	writeError(w, err.Error())
+	return false
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"coverage/client"
+)
+
+func TestTokenStore(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	store, err := NewTokenStore([]Token{
+		{Name: "ui", Token: "ui-token", Scopes: []string{client.ScopeSearch}},
+		{Name: "ops", Token: "ops-token", Scopes: []string{client.ScopeSearch, client.ScopeAdmin}, ExpiresAt: now.Add(time.Hour)},
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+
+	tests := []struct {
+		token string
+		scope string
+		now   time.Time
+		err   error
+	}{
+		{"ui-token", client.ScopeSearch, now, nil},
+		{"ui-token", client.ScopeAdmin, now, errTokenScope},
+		{"ops-token", client.ScopeAdmin, now, nil},
+		{"ops-token", client.ScopeAdmin, now.Add(time.Hour), errTokenExpired},
+		{"ui-token ", client.ScopeSearch, now, errTokenInvalid},
+		{"", client.ScopeSearch, now, errTokenInvalid},
+	}
+	for caseNum, testItem := range tests {
+		if _, err := store.Authenticate(testItem.token, testItem.scope, testItem.now); err != testItem.err {
+			t.Errorf("[%d] wrong result, expected %v, got %v", caseNum, testItem.err, err)
+		}
+	}
+
+	bad := [][]Token{
+		{{Name: "empty"}},
+		{{Name: "a", Token: "x", Scopes: []string{"root"}}},
+		{{Name: "a", Token: "x"}, {Name: "b", Token: "x"}},
+	}
+	for caseNum, tokens := range bad {
+		if _, err := NewTokenStore(tokens); err == nil {
+			t.Errorf("[%d] expected error, got nil", caseNum)
+		}
+	}
+}
+
+func TestTokensReload(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "tokens.json")
+	now := time.Now()
+	writeDataset(t, fileName, `{"tokens": [{"name": "a", "token": "first", "scopes": ["search"]}]}`, now)
+
+	cache := &tokenCache{fileName: fileName}
+	store, err := cache.get()
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	if _, err := store.Authenticate("first", client.ScopeSearch, now); err != nil {
+		t.Errorf("expected nil, got error: %v", err)
+	}
+
+	writeDataset(t, fileName, `{"tokens": [{"name": "a", "token": "second", "scopes": ["search"]}]}`, now.Add(time.Second))
+	// пока файл не перечитан, отдаётся прежняя версия
+	if again, _ := cache.get(); again != store {
+		t.Errorf("get must not reload tokens")
+	}
+	if err := cache.reload(); err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	store, _ = cache.get()
+	if _, err := store.Authenticate("first", client.ScopeSearch, now); err != errTokenInvalid {
+		t.Errorf("wrong result, expected %v for rotated token, got %v", errTokenInvalid, err)
+	}
+
+	// битый файл не отключает авторизацию
+	writeDataset(t, fileName, `{"tokens": [`, now.Add(2*time.Second))
+	if err := cache.reload(); err == nil {
+		t.Errorf("expected error for broken file, got nil")
+	}
+	store, err = cache.get()
+	if err != nil {
+		t.Fatalf("expected previous tokens, got error: %v", err)
+	}
+	if _, err := store.Authenticate("second", client.ScopeSearch, now); err != nil {
+		t.Errorf("expected nil, got error: %v", err)
+	}
+}
+
+func TestTokensWatch(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "tokens.json")
+	now := time.Now()
+	writeDataset(t, fileName, `{"tokens": [{"name": "a", "token": "first", "scopes": ["search"]}]}`, now)
+	srv := New(NewMemoryStore([]client.User{{Id: 1}}), fileName, Options{TokensReloadInterval: 10 * time.Millisecond})
+	defer srv.Close()
+	watchTs := httptest.NewServer(srv)
+	defer watchTs.Close()
+
+	s := &client.SearchClient{AccessToken: "first", URL: watchTs.URL}
+	if _, err := s.FindUsers(client.SearchRequest{Limit: 1}); err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	writeDataset(t, fileName, `{"tokens": [{"name": "a", "token": "second", "scopes": ["search"]}]}`, now.Add(time.Second))
+	s.AccessToken = "second"
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		_, err := s.FindUsers(client.SearchRequest{Limit: 1})
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("rotated token was not picked up: %v", err)
+		}
+	}
+}
+
+func TestReloadServerScope(t *testing.T) {
+	adminTs := httptest.NewServer(http.HandlerFunc(searchServer.Reload))
+	defer adminTs.Close()
+
+	tests := []struct {
+		token  string
+		status int
+		body   string
+	}{
+		{"AdminToken", http.StatusOK, `{"users":35}`},
+		{token, http.StatusForbidden, errTokenScope.Error()},
+		{"", http.StatusUnauthorized, errTokenInvalid.Error()},
+	}
+	for caseNum, testItem := range tests {
+		req, _ := http.NewRequest("POST", adminTs.URL, nil)
+		req.Header.Set("AccessToken", testItem.token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("[%d] expected nil, got error: %v", caseNum, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != testItem.status || !strings.Contains(string(body), testItem.body) {
+			t.Errorf("[%d] wrong result, expected %d %s, got %d %s", caseNum, testItem.status, testItem.body, resp.StatusCode, body)
+		}
+		if resp.StatusCode != http.StatusOK {
+			errResp := client.SearchErrorResponse{}
+			if err := json.Unmarshal(body, &errResp); err != nil {
+				t.Errorf("[%d] expected SearchErrorResponse, got %s", caseNum, body)
+			}
+		}
+	}
+}
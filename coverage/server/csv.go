@@ -0,0 +1,107 @@
+package server
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// NewCSVStore читает CSV, первая строка которого - имена полей row из dataset.xml в любом порядке:
+//
+//	id,first_name,last_name,age,isActive
+//	1,Boyd,Wolf,22,true
+//
+// id обязателен, остальные колонки можно опускать. Пустое значение - ноль или false
+func NewCSVStore(fileName string) (*Store, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, recordError(1, 0, "empty file, expected header")
+	}
+	if err != nil {
+		return nil, csvError(err, 0)
+	}
+	setters := make([]func(r *row, value string) error, len(header))
+	seen := map[string]bool{}
+	idColumn := -1
+	for i, column := range header {
+		column = strings.TrimSpace(column)
+		if i == 0 {
+			column = strings.TrimPrefix(column, "\ufeff")
+		}
+		setter, ok := rowColumns[column]
+		if !ok {
+			return nil, recordError(1, 0, "unknown column %q", column)
+		}
+		if seen[column] {
+			return nil, recordError(1, 0, "duplicate column %q", column)
+		}
+		seen[column] = true
+		if column == requiredColumn {
+			idColumn = i
+		}
+		header[i], setters[i] = column, setter
+	}
+	if idColumn < 0 {
+		return nil, recordError(1, 0, "no %s column", requiredColumn)
+	}
+
+	set := newRowSet()
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, csvError(err, set.next())
+		}
+		line, _ := reader.FieldPos(0)
+		if record[idColumn] == "" {
+			return nil, recordError(line, set.next(), "empty %s", requiredColumn)
+		}
+		r := row{}
+		for i, value := range record {
+			if err := setters[i](&r, value); err != nil {
+				return nil, recordError(line, set.next(), "%s: %v", header[i], err)
+			}
+		}
+		if err := set.add(r, line); err != nil {
+			return nil, err
+		}
+	}
+	return set.store(), nil
+}
+
+// csvError переносит номер строки из ошибки encoding/csv в RecordError
+func csvError(err error, record int) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return recordError(parseErr.Line, record, "%v", parseErr.Err)
+	}
+	return &parseError{err}
+}
+
+// parseCSVInt - число или пустое значение
+func parseCSVInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// parseCSVBool - true/false или пустое значение
+func parseCSVBool(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
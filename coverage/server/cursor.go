@@ -0,0 +1,108 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"coverage/client"
+)
+
+var (
+	errBadCursor      = errors.New("bad cursor")
+	errBadCursorOrder = errors.New("cursor does not match sort order")
+)
+
+func randomSecret() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return secret
+}
+
+// cursor - место в упорядоченном списке: сразу после пользователя со значениями ключей
+// Num/Str или, если Prev, перед ним
+type cursor struct {
+	Spec string    `json:"s"`
+	Num  []float64 `json:"n"`
+	Str  []string  `json:"t"`
+	Prev bool      `json:"p,omitempty"`
+}
+
+func newCursor(plan *sortPlan, user *client.User, prev bool) *cursor {
+	c := &cursor{Spec: plan.spec(), Prev: prev}
+	for _, v := range plan.values(user) {
+		c.Num = append(c.Num, v.num)
+		c.Str = append(c.Str, v.str)
+	}
+	return c
+}
+
+func (c *cursor) values() []filterValue {
+	values := make([]filterValue, len(c.Num))
+	for i := range values {
+		values[i] = filterValue{num: c.Num[i], str: c.Str[i]}
+	}
+	return values
+}
+
+func cursorSignature(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// encode - непрозрачная для клиента строка: данные и их подпись ключом secret
+func (c *cursor) encode(secret []byte) string {
+	data, _ := json.Marshal(c)
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + cursorSignature(secret, payload)
+}
+
+// decodeCursor проверяет подпись и то, что курсор выдан для того же порядка
+func decodeCursor(token string, plan *sortPlan, secret []byte) (*cursor, error) {
+	dot := strings.IndexByte(token, '.')
+	if dot < 0 {
+		return nil, errBadCursor
+	}
+	payload, signature := token[:dot], token[dot+1:]
+	if !hmac.Equal([]byte(signature), []byte(cursorSignature(secret, payload))) {
+		return nil, errBadCursor
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, errBadCursor
+	}
+	c := &cursor{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, errBadCursor
+	}
+	if c.Spec != plan.spec() || len(c.Num) != len(c.Str) || len(c.Num) != len(plan.values(&client.User{})) {
+		return nil, errBadCursorOrder
+	}
+	return c, nil
+}
+
+// start - с какого пользователя начинается страница из limit записей по курсору
+func (c *cursor) start(plan *sortPlan, users []client.User, limit int) int {
+	pos := plan.search(users, c.values())
+	if !c.Prev {
+		// пропускаем самого пользователя из курсора, если он всё ещё есть
+		if pos < len(users) && plan.compare(plan.values(&users[pos]), c.values()) == 0 {
+			pos++
+		}
+		return pos
+	}
+
+	pos -= limit
+	if pos < 0 {
+		pos = 0
+	}
+	return pos
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"coverage/client"
+)
+
+func userIds(users []client.User) []int {
+	ids := []int{}
+	for _, user := range users {
+		ids = append(ids, user.Id)
+	}
+	return ids
+}
+
+func TestCursorPaging(t *testing.T) {
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         ts.URL,
+	}
+	sortKeys := []client.SortKey{{Field: "Age", Desc: true}}
+
+	all, err := s.FindUsers(client.SearchRequest{Limit: 25, Sort: sortKeys, Query: "NOT Boyd"})
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	rest, err := s.FindUsers(client.SearchRequest{Limit: 25, Offset: 25, Sort: sortKeys, Query: "NOT Boyd"})
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	expected := append(userIds(all.Users), userIds(rest.Users)...)
+
+	// вперёд по курсорам
+	var pages [][]int
+	var ids []int
+	req := client.SearchRequest{Limit: 4, Sort: sortKeys, Query: "NOT Boyd"}
+	for {
+		result, err := s.FindUsers(req)
+		if err != nil {
+			t.Fatalf("[%d] expected nil, got error: %v", len(pages), err)
+		}
+		pages = append(pages, userIds(result.Users))
+		ids = append(ids, userIds(result.Users)...)
+		if (len(pages) == 1) != (result.PrevCursor == "") {
+			t.Errorf("[%d] PrevCursor must be empty only on the first page, got %q", len(pages)-1, result.PrevCursor)
+		}
+		if result.NextCursor == "" {
+			if result.NextPage {
+				t.Errorf("[%d] NextPage without NextCursor", len(pages)-1)
+			}
+			req.Cursor = result.PrevCursor
+			break
+		}
+		req.Cursor = result.NextCursor
+	}
+	if !reflect.DeepEqual(expected, ids) {
+		t.Fatalf("wrong result, expected %v, got %v", expected, ids)
+	}
+
+	// и обратно
+	for i := len(pages) - 2; i >= 0; i-- {
+		result, err := s.FindUsers(req)
+		if err != nil {
+			t.Fatalf("[%d] expected nil, got error: %v", i, err)
+		}
+		if !reflect.DeepEqual(pages[i], userIds(result.Users)) || !result.NextPage {
+			t.Errorf("[%d] wrong result, expected %v, got %v", i, pages[i], userIds(result.Users))
+		}
+		req.Cursor = result.PrevCursor
+	}
+	if req.Cursor != "" {
+		t.Errorf("first page must have no PrevCursor")
+	}
+}
+
+func TestCursorStableAfterChange(t *testing.T) {
+	users := []client.User{{Id: 1, Age: 30}, {Id: 2, Age: 20}, {Id: 3, Age: 40}}
+	plan := newSortPlan([]client.SortKey{{Field: "Age", Desc: false}}, nil)
+	plan.sort(users)
+	c := newCursor(plan, &users[1], false) // после Id 1, Age 30
+
+	// перед курсором добавили пользователя, а сам пользователь из курсора пропал
+	changed := []client.User{{Id: 4, Age: 25}, {Id: 2, Age: 20}, {Id: 3, Age: 40}, {Id: 5, Age: 35}}
+	plan.sort(changed)
+	start := c.start(plan, changed, 3)
+	if ids := userIds(changed[start:]); !reflect.DeepEqual(ids, []int{5, 3}) {
+		t.Errorf("wrong result, expected [5 3], got %v", ids)
+	}
+}
+
+func TestCursorBadRequest(t *testing.T) {
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         ts.URL,
+	}
+	result, err := s.FindUsers(client.SearchRequest{Limit: 2, OrderBy: 1, OrderField: "Age"})
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+
+	tests := []TestCase{
+		{
+			Request: client.SearchRequest{Limit: 2, OrderBy: 1, OrderField: "Age", Cursor: result.NextCursor + "x"},
+			Result:  Result{nil, errBadCursor},
+		},
+		{
+			Request: client.SearchRequest{Limit: 2, OrderBy: 1, OrderField: "Age", Cursor: "garbage"},
+			Result:  Result{nil, errBadCursor},
+		},
+		{
+			Request: client.SearchRequest{Limit: 2, OrderBy: -1, OrderField: "Age", Cursor: result.NextCursor},
+			Result:  Result{nil, errBadCursorOrder},
+		},
+	}
+	for caseNum, testItem := range tests {
+		_, err := s.FindUsers(testItem.Request)
+		if err == nil || !strings.Contains(err.Error(), testItem.Result.Error.Error()) {
+			t.Errorf("[%d] wrong result, expected %v, got %v", caseNum, testItem.Result.Error, err)
+		}
+	}
+}
+
+// NextCursor указывает сразу за последней отданной записью, и без FindUsers тоже
+func TestCursorRawRequest(t *testing.T) {
+	var pages [][]int
+	params := url.Values{"limit": {"2"}, "offset": {"0"}, "order_by": {"0"}, "sort": {"Id"}}
+	for len(pages) < 2 {
+		req, _ := http.NewRequest("GET", ts.URL+"?"+params.Encode(), nil)
+		req.Header.Set("AccessToken", token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("expected nil, got error: %v", err)
+		}
+		envelope := client.SearchEnvelope{}
+		err = json.NewDecoder(resp.Body).Decode(&envelope)
+		resp.Body.Close()
+		if err != nil || envelope.NextCursor == "" {
+			t.Fatalf("wrong result, expected page with next_cursor, got %#v, %v", envelope, err)
+		}
+		pages = append(pages, userIds(envelope.Users))
+		params.Set("cursor", envelope.NextCursor)
+	}
+	if !reflect.DeepEqual(pages, [][]int{{0, 1}, {2, 3}}) {
+		t.Errorf("wrong result, expected [[0 1] [2 3]], got %v", pages)
+	}
+}
+
+func TestCursorSecret(t *testing.T) {
+	store := NewMemoryStore([]client.User{{Id: 1}, {Id: 2}, {Id: 3}})
+	shared := Options{CursorSecret: []byte("shared secret")}
+	firstTs := httptest.NewServer(New(store, testTokens, shared))
+	defer firstTs.Close()
+	secondTs := httptest.NewServer(New(store, testTokens, shared))
+	defer secondTs.Close()
+	otherTs := httptest.NewServer(New(store, testTokens, Options{}))
+	defer otherTs.Close()
+
+	s := &client.SearchClient{AccessToken: token, URL: firstTs.URL}
+	req := client.SearchRequest{Limit: 1, Sort: []client.SortKey{{Field: "Id"}}}
+	result, err := s.FindUsers(req)
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+
+	// копия сервера с тем же ключом принимает курсор, с другим ключом - нет
+	req.Cursor = result.NextCursor
+	s.URL = secondTs.URL
+	if result, err := s.FindUsers(req); err != nil || !reflect.DeepEqual(userIds(result.Users), []int{2}) {
+		t.Errorf("wrong result, expected [2], got %v, %v", result, err)
+	}
+	s.URL = otherTs.URL
+	if _, err := s.FindUsers(req); err == nil || !strings.Contains(err.Error(), errBadCursor.Error()) {
+		t.Errorf("wrong result, expected %v, got %v", errBadCursor, err)
+	}
+}
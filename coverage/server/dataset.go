@@ -0,0 +1,109 @@
+package server
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultReloadInterval - как часто NewXMLFileStore и NewJSONLinesFileStore проверяют,
+// не изменился ли файл с данными
+const DefaultReloadInterval = 5 * time.Second
+
+// Dataset - Store, который подменяется целиком, когда файл на диске меняется.
+// Запросы, уже получившие Store, дорабатывают со старой версией
+type Dataset struct {
+	fileName string
+	load     Loader
+	store    atomic.Value // *Store
+
+	mu      sync.Mutex // защищает всё ниже и не даёт двум Reload идти параллельно
+	modTime time.Time
+	size    int64
+	lastErr error
+}
+
+// OpenDataset загружает файл через load; если это не удалось, Dataset не создаётся
+func OpenDataset(fileName string, load Loader) (*Dataset, error) {
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return nil, err
+	}
+	store, err := load(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Dataset{
+		fileName: fileName,
+		load:     load,
+		modTime:  info.ModTime(),
+		size:     info.Size(),
+	}
+	d.store.Store(store)
+	return d, nil
+}
+
+// Store - текущая версия данных
+func (d *Dataset) Store() *Store {
+	return d.store.Load().(*Store)
+}
+
+// Err - ошибка последней неудачной перезагрузки, nil если последняя прошла успешно
+func (d *Dataset) Err() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.lastErr
+}
+
+// Reload перечитывает файл, если у него поменялись mtime или размер.
+// При ошибке продолжаем отдавать предыдущую версию
+func (d *Dataset) Reload() (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	info, err := os.Stat(d.fileName)
+	if err != nil {
+		d.lastErr = err
+		return false, err
+	}
+	if info.ModTime().Equal(d.modTime) && info.Size() == d.size {
+		return false, nil
+	}
+
+	store, err := d.load(d.fileName)
+	// запоминаем mtime и при ошибке, чтобы не разбирать тот же битый файл на каждой проверке
+	d.modTime = info.ModTime()
+	d.size = info.Size()
+	if err != nil {
+		d.lastErr = err
+		return false, err
+	}
+	d.store.Store(store)
+	d.lastErr = nil
+	return true, nil
+}
+
+// Watch раз в interval вызывает Reload, пока не вызовут stop.
+// Ошибки перезагрузки передаются в onError
+func (d *Dataset) Watch(interval time.Duration, onError func(error)) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := d.Reload(); err != nil && onError != nil {
+					onError(err)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
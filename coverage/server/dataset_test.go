@@ -0,0 +1,99 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const datasetTwoRows = `<root>
+<row><id>1</id><first_name>Boyd</first_name><last_name>Wolf</last_name></row>
+<row><id>2</id><first_name>Hilda</first_name><last_name>Mayer</last_name></row>
+</root>`
+
+func writeDataset(t *testing.T, fileName, data string, modTime time.Time) {
+	if err := ioutil.WriteFile(fileName, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// явно двигаем mtime, иначе на быстрой файловой системе он может не измениться
+	if err := os.Chtimes(fileName, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDatasetReload(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "dataset.xml")
+	now := time.Now()
+	writeDataset(t, fileName, `<root><row><id>1</id></row></root>`, now)
+
+	d, err := OpenDataset(fileName, NewStore)
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	old := d.Store()
+
+	changed, err := d.Reload()
+	if changed || err != nil {
+		t.Errorf("file not changed, expected false and nil, got %v, %v", changed, err)
+	}
+
+	writeDataset(t, fileName, datasetTwoRows, now.Add(time.Second))
+	changed, err = d.Reload()
+	if !changed || err != nil {
+		t.Errorf("expected true and nil, got %v, %v", changed, err)
+	}
+	if d.Store().Len() != 2 {
+		t.Errorf("wrong result, expected 2 users, got %v", d.Store().Len())
+	}
+	if old.Len() != 1 {
+		t.Errorf("previous Store must not change, got %v users", old.Len())
+	}
+
+	writeDataset(t, fileName, "<root><row>", now.Add(2*time.Second))
+	changed, err = d.Reload()
+	if changed || err == nil {
+		t.Errorf("expected false and error, got %v, %v", changed, err)
+	}
+	if d.Store().Len() != 2 || d.Err() == nil {
+		t.Errorf("broken file must keep previous version, got %v users, error %v", d.Store().Len(), d.Err())
+	}
+}
+
+func TestDatasetWatch(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "dataset.xml")
+	now := time.Now()
+	writeDataset(t, fileName, `<root><row><id>1</id></row></root>`, now)
+
+	d, err := OpenDataset(fileName, NewStore)
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	errs := make(chan error, 1)
+	stop := d.Watch(10*time.Millisecond, func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	})
+	defer stop()
+
+	writeDataset(t, fileName, datasetTwoRows, now.Add(time.Second))
+	for deadline := time.Now().Add(time.Second); d.Store().Len() != 2; {
+		if time.Now().After(deadline) {
+			t.Fatalf("dataset was not reloaded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	writeDataset(t, fileName, "broken", now.Add(2*time.Second))
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Errorf("expected reload error")
+	}
+	if d.Store().Len() != 2 {
+		t.Errorf("broken file must keep previous version, got %v users", d.Store().Len())
+	}
+}
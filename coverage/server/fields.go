@@ -0,0 +1,66 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"coverage/client"
+)
+
+// extraFields - поля User сверх базовых пяти, которые можно запросить через fields
+var extraFields = map[string]func(dst, src *client.User){
+	"GUID":          func(dst, src *client.User) { dst.GUID = src.GUID },
+	"IsActive":      func(dst, src *client.User) { dst.IsActive = src.IsActive },
+	"Balance":       func(dst, src *client.User) { dst.Balance = src.Balance },
+	"Picture":       func(dst, src *client.User) { dst.Picture = src.Picture },
+	"EyeColor":      func(dst, src *client.User) { dst.EyeColor = src.EyeColor },
+	"Company":       func(dst, src *client.User) { dst.Company = src.Company },
+	"Email":         func(dst, src *client.User) { dst.Email = src.Email },
+	"Phone":         func(dst, src *client.User) { dst.Phone = src.Phone },
+	"Address":       func(dst, src *client.User) { dst.Address = src.Address },
+	"Registered":    func(dst, src *client.User) { dst.Registered = src.Registered },
+	"FavoriteFruit": func(dst, src *client.User) { dst.FavoriteFruit = src.FavoriteFruit },
+}
+
+// parseFields разбирает параметр fields: имена через запятую или FieldsAll.
+// nil значит "все поля"
+func parseFields(param string) ([]string, error) {
+	if param == "" {
+		return []string{}, nil
+	}
+	if param == client.FieldsAll {
+		return nil, nil
+	}
+
+	fields := strings.Split(param, ",")
+	for i, field := range fields {
+		field = strings.TrimSpace(field)
+		if _, ok := extraFields[field]; !ok {
+			return nil, fmt.Errorf("unknown field %s", field)
+		}
+		fields[i] = field
+	}
+	return fields, nil
+}
+
+// project оставляет у пользователей базовые поля, Score и перечисленные fields
+func project(users []client.User, fields []string) {
+	if fields == nil {
+		return
+	}
+	for i := range users {
+		src := &users[i]
+		dst := client.User{
+			Id:     src.Id,
+			Name:   src.Name,
+			Age:    src.Age,
+			About:  src.About,
+			Gender: src.Gender,
+			Score:  src.Score,
+		}
+		for _, field := range fields {
+			extraFields[field](&dst, src)
+		}
+		users[i] = dst
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"coverage/client"
+)
+
+func TestFields(t *testing.T) {
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         ts.URL,
+	}
+	base := client.User{
+		Id:     0,
+		Name:   "Boyd Wolf",
+		Age:    22,
+		About:  "Nulla cillum enim voluptate consequat laborum esse excepteur occaecat commodo nostrud excepteur ut cupidatat. Occaecat minim incididunt ut proident ad sint nostrud ad laborum sint pariatur. Ut nulla commodo dolore officia. Consequat anim eiusmod amet commodo eiusmod deserunt culpa. Ea sit dolore nostrud cillum proident nisi mollit est Lorem pariatur. Lorem aute officia deserunt dolor nisi aliqua consequat nulla nostrud ipsum irure id deserunt dolore. Minim reprehenderit nulla exercitation labore ipsum.\n",
+		Gender: "male",
+	}
+	inactive := base
+	inactive.IsActive = new(bool)
+	withContacts := base
+	withContacts.Email = "boydwolf@hopeli.com"
+	withContacts.Company = "HOPELI"
+	all := withContacts
+	all.IsActive = new(bool)
+	all.GUID = "1a6fa827-62f1-45f6-b579-aaead2b47169"
+	all.Balance = "$2,144.93"
+	all.Picture = "http://placehold.it/32x32"
+	all.EyeColor = "green"
+	all.Phone = "+1 (956) 593-2402"
+	all.Address = "586 Winthrop Street, Edneyville, Mississippi, 9555"
+	all.Registered = "2017-02-05T06:23:27 -03:00"
+	all.FavoriteFruit = "apple"
+
+	tests := []struct {
+		fields []string
+		result client.User
+	}{
+		{fields: nil, result: base},
+		{fields: []string{"Email", "Company"}, result: withContacts},
+		// false приходит, раз его запросили
+		{fields: []string{"IsActive"}, result: inactive},
+		{fields: []string{client.FieldsAll}, result: all},
+	}
+	for caseNum, testItem := range tests {
+		result, err := s.FindUsers(client.SearchRequest{
+			Limit:      1,
+			Query:      "Boyd",
+			OrderBy:    1,
+			OrderField: "Id",
+			Fields:     testItem.fields,
+		})
+		if err != nil {
+			t.Errorf("[%d] expected nil, got error: %v", caseNum, err)
+			continue
+		}
+		if len(result.Users) != 1 || !reflect.DeepEqual(testItem.result, result.Users[0]) {
+			t.Errorf("[%d] wrong result, expected %#v, got %#v", caseNum, testItem.result, result.Users)
+		}
+	}
+
+	_, err := s.FindUsers(client.SearchRequest{Fields: []string{"Email", "Password"}})
+	if err == nil || !strings.Contains(err.Error(), "unknown field Password") {
+		t.Errorf("wrong result, expected unknown field error, got %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"coverage/client"
+)
+
+type fieldKind int
+
+const (
+	kindString fieldKind = iota
+	kindInt
+	kindBool
+	kindTime
+	kindMoney
+)
+
+// registeredLayout - формат даты registered в dataset.xml
+const registeredLayout = "2006-01-02T15:04:05 -07:00"
+
+// filterFields - поля, по которым можно фильтровать, имена как в row
+var filterFields = map[string]struct {
+	kind  fieldKind
+	value func(u *client.User) string
+}{
+	"id":            {kindInt, func(u *client.User) string { return strconv.Itoa(u.Id) }},
+	"age":           {kindInt, func(u *client.User) string { return strconv.Itoa(u.Age) }},
+	"gender":        {kindString, func(u *client.User) string { return u.Gender }},
+	"isActive":      {kindBool, func(u *client.User) string { return strconv.FormatBool(u.IsActive != nil && *u.IsActive) }},
+	"eyeColor":      {kindString, func(u *client.User) string { return u.EyeColor }},
+	"company":       {kindString, func(u *client.User) string { return u.Company }},
+	"email":         {kindString, func(u *client.User) string { return u.Email }},
+	"phone":         {kindString, func(u *client.User) string { return u.Phone }},
+	"address":       {kindString, func(u *client.User) string { return u.Address }},
+	"favoriteFruit": {kindString, func(u *client.User) string { return u.FavoriteFruit }},
+	"guid":          {kindString, func(u *client.User) string { return u.GUID }},
+	"registered":    {kindTime, func(u *client.User) string { return u.Registered }},
+	"balance":       {kindMoney, func(u *client.User) string { return u.Balance }},
+}
+
+// filterValue - значение поля, приведённое к виду, который можно сравнивать:
+// числа, даты и деньги в num, строки и bool в str
+type filterValue struct {
+	num float64
+	str string
+}
+
+func parseValue(kind fieldKind, s string) (filterValue, error) {
+	switch kind {
+	case kindInt:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return filterValue{}, fmt.Errorf("expects integer, got %q", s)
+		}
+		return filterValue{num: float64(n)}, nil
+	case kindBool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return filterValue{}, fmt.Errorf("expects true or false, got %q", s)
+		}
+		return filterValue{str: strconv.FormatBool(b)}, nil
+	case kindTime:
+		for _, layout := range []string{registeredLayout, time.RFC3339, "2006-01-02"} {
+			if t, err := time.Parse(layout, s); err == nil {
+				return filterValue{num: float64(t.Unix())}, nil
+			}
+		}
+		return filterValue{}, fmt.Errorf("expects date like 2014-05-21 or %s, got %q", registeredLayout, s)
+	case kindMoney:
+		n, err := strconv.ParseFloat(strings.NewReplacer("$", "", ",", "").Replace(s), 64)
+		if err != nil {
+			return filterValue{}, fmt.Errorf("expects amount like $2,144.93, got %q", s)
+		}
+		return filterValue{num: n}, nil
+	}
+	return filterValue{str: s}, nil
+}
+
+// fieldFilter - разобранный и проверенный Filter
+type fieldFilter struct {
+	field  string
+	op     string
+	values []filterValue
+}
+
+// parseFilter разбирает Filter.String(): "age>=30", "gender=female", "eyeColor in (blue,green)"
+func parseFilter(s string) (*fieldFilter, error) {
+	s = strings.TrimSpace(s)
+	end := 0
+	for end < len(s) && (s[end] >= 'a' && s[end] <= 'z' || s[end] >= 'A' && s[end] <= 'Z') {
+		end++
+	}
+	f := &fieldFilter{field: s[:end]}
+	field, ok := filterFields[f.field]
+	if !ok {
+		return nil, fmt.Errorf("bad filter %q: unknown field %q", s, f.field)
+	}
+
+	var rawValues []string
+	rest := strings.TrimSpace(s[end:])
+	if strings.HasPrefix(rest, client.FilterIn+" ") || strings.HasPrefix(rest, client.FilterIn+"(") {
+		list := strings.TrimSpace(rest[len(client.FilterIn):])
+		if !strings.HasPrefix(list, "(") || !strings.HasSuffix(list, ")") {
+			return nil, fmt.Errorf("bad filter %q: expected list in parentheses", s)
+		}
+		f.op = client.FilterIn
+		for _, v := range strings.Split(list[1:len(list)-1], ",") {
+			rawValues = append(rawValues, strings.TrimSpace(v))
+		}
+	} else {
+		// двухсимвольные операторы проверяем раньше односимвольных
+		for _, op := range []string{client.FilterNe, client.FilterGte, client.FilterLte, client.FilterEq, client.FilterGt, client.FilterLt} {
+			if strings.HasPrefix(rest, op) {
+				f.op = op
+				rawValues = []string{strings.TrimSpace(rest[len(op):])}
+				break
+			}
+		}
+		if f.op == "" {
+			return nil, fmt.Errorf("bad filter %q: unknown operator", s)
+		}
+	}
+
+	if field.kind == kindString || field.kind == kindBool {
+		switch f.op {
+		case client.FilterGt, client.FilterGte, client.FilterLt, client.FilterLte:
+			return nil, fmt.Errorf("bad filter %q: operator %s is not allowed for %s", s, f.op, f.field)
+		}
+	}
+	for _, raw := range rawValues {
+		v, err := parseValue(field.kind, raw)
+		if err != nil {
+			return nil, fmt.Errorf("bad filter %q: %s %v", s, f.field, err)
+		}
+		f.values = append(f.values, v)
+	}
+	return f, nil
+}
+
+func (f *fieldFilter) match(u *client.User) bool {
+	field := filterFields[f.field]
+	v, err := parseValue(field.kind, field.value(u))
+	if err != nil {
+		// значение в данных испорчено - под условие оно не подходит
+		return false
+	}
+
+	switch f.op {
+	case client.FilterIn:
+		for _, want := range f.values {
+			if v == want {
+				return true
+			}
+		}
+		return false
+	case client.FilterEq:
+		return v == f.values[0]
+	case client.FilterNe:
+		return v != f.values[0]
+	case client.FilterGt:
+		return v.num > f.values[0].num
+	case client.FilterGte:
+		return v.num >= f.values[0].num
+	case client.FilterLt:
+		return v.num < f.values[0].num
+	case client.FilterLte:
+		return v.num <= f.values[0].num
+	}
+	return false
+}
+
+// applyFilters оставляет пользователей, подходящих под все фильтры
+func applyFilters(users []client.User, filters []*fieldFilter) []client.User {
+	if len(filters) == 0 {
+		return users
+	}
+	result := users[:0]
+	for i := range users {
+		ok := true
+		for _, f := range filters {
+			if !f.match(&users[i]) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			result = append(result, users[i])
+		}
+	}
+	return result
+}
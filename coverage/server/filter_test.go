@@ -0,0 +1,90 @@
+package server
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"coverage/client"
+)
+
+type TestCaseFilter struct {
+	filters []client.Filter
+	result  []int
+}
+
+func TestFilters(t *testing.T) {
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         ts.URL,
+	}
+	tests := []TestCaseFilter{
+		{
+			filters: []client.Filter{{Field: "gender", Op: client.FilterEq, Value: "female"}, {Field: "age", Op: client.FilterGte, Value: "30"}},
+			result:  []int{5, 7, 9, 16, 22, 25, 29, 32, 33},
+		},
+		{
+			filters: []client.Filter{{Field: "eyeColor", Op: client.FilterIn, Value: "blue, green"}, {Field: "isActive", Op: client.FilterEq, Value: "true"}},
+			result:  []int{4, 5, 7, 8, 11, 13, 18, 20, 25, 26, 27, 30, 31, 32, 34},
+		},
+		{
+			filters: []client.Filter{{Field: "registered", Op: client.FilterGte, Value: "2016-01-01"}, {Field: "registered", Op: client.FilterLt, Value: "2017-01-01"}},
+			result:  []int{1, 2, 10, 12, 14, 20, 24, 32},
+		},
+		{
+			filters: []client.Filter{{Field: "balance", Op: client.FilterGte, Value: "$3,000"}, {Field: "balance", Op: client.FilterLte, Value: "3500"}},
+			result:  []int{7, 8, 14, 17, 20, 22, 23, 24, 29, 33},
+		},
+		{
+			filters: []client.Filter{{Field: "gender", Op: client.FilterNe, Value: "male"}, {Field: "id", Op: client.FilterLt, Value: "3"}},
+			result:  []int{1},
+		},
+	}
+
+	for caseNum, testItem := range tests {
+		result, err := s.FindUsers(client.SearchRequest{
+			Limit:      25,
+			OrderBy:    1,
+			OrderField: "Id",
+			Filters:    testItem.filters,
+		})
+		if err != nil {
+			t.Errorf("[%d] expected nil, got error: %v", caseNum, err)
+			continue
+		}
+		var ids []int
+		for _, user := range result.Users {
+			ids = append(ids, user.Id)
+		}
+		if !reflect.DeepEqual(testItem.result, ids) {
+			t.Errorf("[%d] wrong result, expected %v, got %v", caseNum, testItem.result, ids)
+		}
+	}
+}
+
+func TestFiltersBadRequest(t *testing.T) {
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         ts.URL,
+	}
+	tests := []TestCaseFilter{
+		{filters: []client.Filter{{Field: "password", Op: client.FilterEq, Value: "1"}}},
+		{filters: []client.Filter{{Field: "age", Op: client.FilterGte, Value: "old"}}},
+		{filters: []client.Filter{{Field: "gender", Op: client.FilterGt, Value: "female"}}},
+		{filters: []client.Filter{{Field: "isActive", Op: client.FilterEq, Value: "yes"}}},
+		{filters: []client.Filter{{Field: "registered", Op: client.FilterLt, Value: "yesterday"}}},
+		{filters: []client.Filter{{Field: "balance", Op: client.FilterLt, Value: "a lot"}}},
+		{filters: []client.Filter{{Field: "age", Op: "~", Value: "30"}}},
+	}
+
+	for caseNum, testItem := range tests {
+		_, err := s.FindUsers(client.SearchRequest{Filters: testItem.filters})
+		if err == nil {
+			t.Errorf("[%d] expected error, got nil", caseNum)
+			continue
+		}
+		if !strings.Contains(err.Error(), "bad filter") {
+			t.Errorf("[%d] wrong result, got %#v", caseNum, err.Error())
+		}
+	}
+}
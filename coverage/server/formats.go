@@ -0,0 +1,174 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"coverage/client"
+)
+
+// Format - формат файла с пользователями. Во всех форматах поля называются как у row в dataset.xml
+type Format string
+
+const (
+	// FormatXML - <root><row>...</row></root>, см. NewStore
+	FormatXML Format = "xml"
+	// FormatJSON - массив объектов, см. NewJSONStore
+	FormatJSON Format = "json"
+	// FormatNDJSON - по объекту на строку, см. NewJSONLinesStore
+	FormatNDJSON Format = "ndjson"
+	// FormatCSV - первая строка - имена полей, см. NewCSVStore
+	FormatCSV Format = "csv"
+)
+
+var formatLoaders = map[Format]Loader{
+	FormatXML:    NewStore,
+	FormatJSON:   NewJSONStore,
+	FormatNDJSON: NewJSONLinesStore,
+	FormatCSV:    NewCSVStore,
+}
+
+var formatExtensions = map[string]Format{
+	".xml":    FormatXML,
+	".json":   FormatJSON,
+	".ndjson": FormatNDJSON,
+	".jsonl":  FormatNDJSON,
+	".csv":    FormatCSV,
+}
+
+// sniffSize - сколько первых байт файла смотрит DetectFormat
+const sniffSize = 512
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// DetectFormat определяет формат по расширению файла, а если оно незнакомо - по первым байтам:
+// '<' - XML, '[' - JSON, '{' - NDJSON, остальное считаем CSV
+func DetectFormat(fileName string) (Format, error) {
+	if format, ok := formatExtensions[strings.ToLower(filepath.Ext(fileName))]; ok {
+		return format, nil
+	}
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return sniffFormat(head[:n]), nil
+}
+
+func sniffFormat(head []byte) Format {
+	head = bytes.TrimLeft(bytes.TrimPrefix(head, utf8BOM), " \t\r\n")
+	if len(head) == 0 {
+		return FormatCSV
+	}
+	switch head[0] {
+	case '<':
+		return FormatXML
+	case '[':
+		return FormatJSON
+	case '{':
+		return FormatNDJSON
+	}
+	return FormatCSV
+}
+
+// LoadDataset - Loader для файла любого формата, формат определяет DetectFormat
+func LoadDataset(fileName string) (*Store, error) {
+	return LoadDatasetProgress(nil)(fileName)
+}
+
+// RecordError - ошибка в конкретной записи файла с пользователями.
+// Приходит завёрнутой в ошибку разбора, достаётся через errors.As
+type RecordError struct {
+	// строка файла с единицы; 0 - неизвестна
+	Line int
+	// номер записи с единицы; 0 - ошибка не в записи, например в заголовке CSV
+	Record int
+	Err    error
+}
+
+func (e *RecordError) Error() string {
+	switch {
+	case e.Line > 0 && e.Record > 0:
+		return fmt.Sprintf("line %d, record %d: %v", e.Line, e.Record, e.Err)
+	case e.Record > 0:
+		return fmt.Sprintf("record %d: %v", e.Record, e.Err)
+	case e.Line > 0:
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return e.Err.Error()
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// recordError - ошибка разбора файла, указывающая на запись
+func recordError(line, record int, format string, args ...interface{}) error {
+	return &parseError{&RecordError{Line: line, Record: record, Err: fmt.Errorf(format, args...)}}
+}
+
+// rowColumns - поля row по именам из dataset.xml. По ним проверяются ключи JSON
+// и заголовок CSV, а значения CSV через них записываются в row
+var rowColumns = map[string]func(r *row, value string) error{
+	"id":            func(r *row, value string) (err error) { r.ID, err = strconv.Atoi(value); return },
+	"guid":          func(r *row, value string) error { r.GUID = value; return nil },
+	"isActive":      func(r *row, value string) (err error) { r.IsActive, err = parseCSVBool(value); return },
+	"balance":       func(r *row, value string) error { r.Balance = value; return nil },
+	"picture":       func(r *row, value string) error { r.Picture = value; return nil },
+	"age":           func(r *row, value string) (err error) { r.Age, err = parseCSVInt(value); return },
+	"eyeColor":      func(r *row, value string) error { r.EyeColor = value; return nil },
+	"first_name":    func(r *row, value string) error { r.FirstName = value; return nil },
+	"last_name":     func(r *row, value string) error { r.LastName = value; return nil },
+	"gender":        func(r *row, value string) error { r.Gender = value; return nil },
+	"company":       func(r *row, value string) error { r.Company = value; return nil },
+	"email":         func(r *row, value string) error { r.Email = value; return nil },
+	"phone":         func(r *row, value string) error { r.Phone = value; return nil },
+	"address":       func(r *row, value string) error { r.Address = value; return nil },
+	"about":         func(r *row, value string) error { r.About = value; return nil },
+	"registered":    func(r *row, value string) error { r.Registered = value; return nil },
+	"favoriteFruit": func(r *row, value string) error { r.FavoriteFruit = value; return nil },
+}
+
+// requiredColumn - без id запись не принимается ни в одном формате
+const requiredColumn = "id"
+
+// rowSet собирает пользователей из записей файла и следит, чтобы Id не повторялись
+type rowSet struct {
+	users []client.User
+	ids   map[int]int // Id -> номер записи, где он встретился
+}
+
+func newRowSet() *rowSet {
+	return &rowSet{users: []client.User{}, ids: map[int]int{}}
+}
+
+// next - номер очередной записи
+func (s *rowSet) next() int {
+	return len(s.users) + 1
+}
+
+// add добавляет очередную запись, найденную на строке line
+func (s *rowSet) add(r row, line int) error {
+	record := s.next()
+	if first, ok := s.ids[r.ID]; ok {
+		return recordError(line, record, "duplicate id %d, first seen in record %d", r.ID, first)
+	}
+	s.ids[r.ID] = record
+	s.users = append(s.users, rowToUser(r))
+	return nil
+}
+
+func (s *rowSet) store() *Store {
+	return NewMemoryStore(s.users)
+}
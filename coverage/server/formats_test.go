@@ -0,0 +1,154 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectFormat(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name   string
+		data   string
+		format Format
+	}{
+		{"users.xml", "", FormatXML},
+		{"users.JSON", "", FormatJSON},
+		{"users.jsonl", "", FormatNDJSON},
+		{"users.ndjson", "", FormatNDJSON},
+		{"users.csv", "", FormatCSV},
+		{"export-1", "\xef\xbb\xbf  <root></root>", FormatXML},
+		{"export-2", "\n[{\"id\": 1}]", FormatJSON},
+		{"export-3", "{\"id\": 1}\n", FormatNDJSON},
+		{"export-4", "id,first_name\n1,Boyd\n", FormatCSV},
+	}
+	for _, testItem := range tests {
+		fileName := filepath.Join(dir, testItem.name)
+		writeDataset(t, fileName, testItem.data, time.Now())
+		if format, err := DetectFormat(fileName); format != testItem.format || err != nil {
+			t.Errorf("%s: wrong result, expected %v, got %v, %v", testItem.name, testItem.format, format, err)
+		}
+	}
+}
+
+func TestLoadDatasetFormats(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"users.xml": datasetTwoRows,
+		"users.json": `[
+  {"id": 1, "first_name": "Boyd", "last_name": "Wolf"},
+  {"id": 2, "first_name": "Hilda", "last_name": "Mayer"}
+]`,
+		"users.ndjson": `{"id": 1, "first_name": "Boyd", "last_name": "Wolf"}
+{"id": 2, "first_name": "Hilda", "last_name": "Mayer"}`,
+		"users.csv": "last_name,id,first_name\nWolf,1,Boyd\nMayer,2,Hilda\n",
+		// формат по содержимому
+		"users.export": "id,first_name,last_name\n1,Boyd,Wolf\n2,Hilda,Mayer\n",
+	}
+
+	expected, _ := parseStore([]byte(datasetTwoRows))
+	for name, data := range files {
+		fileName := filepath.Join(dir, name)
+		writeDataset(t, fileName, data, time.Now())
+		store, err := LoadDataset(fileName)
+		if err != nil {
+			t.Errorf("%s: expected nil, got error: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(expected.users, store.users) {
+			t.Errorf("%s: wrong result, expected %v, got %v", name, expected.users, store.users)
+		}
+	}
+}
+
+func TestCSVStoreTypes(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "users.csv")
+	writeDataset(t, fileName, "\xef\xbb\xbfid,age,isActive,about\n7,31,true,\"Lorem, ipsum\"\n8,,,\n", time.Now())
+
+	store, err := NewCSVStore(fileName)
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	user, _, _ := store.Get(7)
+	if user.Age != 31 || !*user.IsActive || user.About != "Lorem, ipsum" {
+		t.Errorf("wrong result, got %+v", user)
+	}
+	if user, ok, _ := store.Get(8); !ok || user.Age != 0 || *user.IsActive {
+		t.Errorf("wrong result, expected empty values as zero, got %+v", user)
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		line   int
+		record int
+		text   string
+	}{
+		{"users.json", "[\n{\"id\": 1},\n{\"id\": 2, \"nickname\": \"x\"}\n]", 3, 2, `unknown fields ["nickname"]`},
+		{"users.json", "[\n{\"id\": 1},\n{\"id\": \"2\"}\n]", 3, 2, "cannot unmarshal string"},
+		{"users.json", "[\n{\"id\": 1},\n\n  {\"first_name\": \"Boyd\"}]", 4, 2, "no id"},
+		{"users.json", "[\n{\"id\": 1},\n{\"id\": 1}\n]", 3, 2, "duplicate id 1, first seen in record 1"},
+		{"users.json", "[\n{\"id\": 1},\n{\"id\": 2,\n]", 4, 2, "invalid character"},
+		{"users.json", "[\n  7\n]", 2, 1, "record is not an object"},
+		{"users.json", `{"id": 1}`, 1, 0, "expected array"},
+		{"users.ndjson", "{\"id\": 1}\n\n{\"id\": 2, \"age\": \"old\"}\n", 3, 2, "cannot unmarshal string"},
+		{"users.csv", "id,nickname\n1,x\n", 1, 0, `unknown column "nickname"`},
+		{"users.csv", "first_name\nBoyd\n", 1, 0, "no id column"},
+		{"users.csv", "id,id\n1,1\n", 1, 0, `duplicate column "id"`},
+		{"users.csv", "id,age\n1,22\n2,old\n", 3, 2, "age: strconv.Atoi"},
+		{"users.csv", "id,age\n1,22\n,30\n", 3, 2, "empty id"},
+		{"users.csv", "id,age\n1,22\n2\n", 3, 2, "wrong number of fields"},
+		{"users.csv", "id,isActive\n1,true\n2,maybe\n", 3, 2, "isActive: strconv.ParseBool"},
+		{"users.csv", "", 1, 0, "empty file"},
+		{"users.xml", "<root>\n<row><id>1</id></row>\n<row><first_name>Boyd</first_name></row>\n</root>", 3, 2, "no id"},
+		{"users.xml", "<root>\n<row><id>1</id></row>\n<row><id>1</id></row>\n</root>", 3, 2, "duplicate id 1, first seen in record 1"},
+	}
+	dir := t.TempDir()
+	for caseNum, testItem := range tests {
+		fileName := filepath.Join(dir, testItem.name)
+		writeDataset(t, fileName, testItem.data, time.Now())
+		_, err := LoadDataset(fileName)
+
+		var recordErr *RecordError
+		var pErr *parseError
+		if !errors.As(err, &recordErr) || !errors.As(err, &pErr) {
+			t.Errorf("[%d] expected RecordError, got %v", caseNum, err)
+			continue
+		}
+		if recordErr.Line != testItem.line || recordErr.Record != testItem.record || !strings.Contains(err.Error(), testItem.text) {
+			t.Errorf("[%d] wrong result, expected line %d, record %d, %q, got %v", caseNum, testItem.line, testItem.record, testItem.text, err)
+		}
+	}
+}
+
+// BenchmarkLoadJSON - большой массив по записи на строку: номера строк не должны
+// пересчитываться с начала файла на каждой записи
+func BenchmarkLoadJSON(b *testing.B) {
+	var data strings.Builder
+	data.WriteString("[\n")
+	for i := 0; i < 50000; i++ {
+		if i > 0 {
+			data.WriteString(",\n")
+		}
+		fmt.Fprintf(&data, `{"id": %d, "first_name": "Boyd", "last_name": "Wolf", "about": "Nulla cillum enim"}`, i)
+	}
+	data.WriteString("\n]")
+	fileName := filepath.Join(b.TempDir(), "users.json")
+	if err := os.WriteFile(fileName, []byte(data.String()), 0644); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewJSONStore(fileName); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
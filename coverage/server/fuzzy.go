@@ -0,0 +1,167 @@
+package server
+
+import "sort"
+
+// fuzzyWeight - во сколько раз слово с опечаткой весит меньше точного при подсчёте Score
+const fuzzyWeight = 0.5
+
+// maxTypos - сколько опечаток прощаем в слове: в коротких словах опечатка
+// быстро превращает их в совсем другое слово
+func maxTypos(word []rune) int {
+	switch {
+	case len(word) < 3:
+		return 0
+	case len(word) == 3:
+		return 1
+	}
+	return 2
+}
+
+// fuzzyIndex ищет слова из имён, похожие на данное, по общим биграммам.
+// Одна правка портит не больше двух биграмм, так что у слова на расстоянии k
+// общих биграмм хотя бы len(bigrams)-2k - остальные слова даже не сравниваем
+type fuzzyIndex struct {
+	terms []string
+	runes [][]rune
+	grams map[string][]int // биграмма -> номера слов в terms по возрастанию
+}
+
+type fuzzyMatch struct {
+	term     string
+	distance int
+}
+
+func bigrams(word []rune) []string {
+	padded := append(append([]rune{'^'}, word...), '$')
+	grams := make([]string, 0, len(padded)-1)
+	for i := 0; i+1 < len(padded); i++ {
+		grams = append(grams, string(padded[i:i+2]))
+	}
+	return grams
+}
+
+func newFuzzyIndex(terms []string) *fuzzyIndex {
+	f := &fuzzyIndex{
+		terms: terms,
+		runes: make([][]rune, len(terms)),
+		grams: map[string][]int{},
+	}
+	for i, term := range terms {
+		f.runes[i] = []rune(term)
+		for _, gram := range distinct(bigrams(f.runes[i])) {
+			f.grams[gram] = append(f.grams[gram], i)
+		}
+	}
+	return f
+}
+
+// distinct - биграммы без повторов, в порядке первого появления
+func distinct(grams []string) []string {
+	seen := make(map[string]bool, len(grams))
+	unique := grams[:0]
+	for _, gram := range grams {
+		if !seen[gram] {
+			seen[gram] = true
+			unique = append(unique, gram)
+		}
+	}
+	return unique
+}
+
+// lookup - слова на расстоянии Левенштейна от 1 до maxTypos(word), word уже после fold
+func (f *fuzzyIndex) lookup(word string) []fuzzyMatch {
+	wordRunes := []rune(word)
+	k := maxTypos(wordRunes)
+	if k == 0 {
+		return nil
+	}
+
+	var candidates []int
+	// повторы не считаем ни здесь, ни в counts: правка убирает не больше двух разных биграмм
+	grams := distinct(bigrams(wordRunes))
+	if threshold := len(grams) - 2*k; threshold > 0 {
+		counts := map[int]int{}
+		for _, gram := range grams {
+			for _, i := range f.grams[gram] {
+				counts[i]++
+			}
+		}
+		for i, count := range counts {
+			if count >= threshold {
+				candidates = append(candidates, i)
+			}
+		}
+		sort.Ints(candidates)
+	} else {
+		// слово слишком короткое, биграммы ничего не отсекают
+		candidates = allDocs(len(f.terms))
+	}
+
+	var matches []fuzzyMatch
+	for _, i := range candidates {
+		if abs(len(f.runes[i])-len(wordRunes)) > k {
+			continue
+		}
+		if d := levenshtein(wordRunes, f.runes[i], k); d > 0 && d <= k {
+			matches = append(matches, fuzzyMatch{f.terms[i], d})
+		}
+	}
+	return matches
+}
+
+// levenshtein - расстояние между a и b; если оно больше k, возвращает k+1
+func levenshtein(a, b []rune, k int) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if cur[j] < rowMin {
+				rowMin = cur[j]
+			}
+		}
+		if rowMin > k {
+			return k + 1
+		}
+		prev, cur = cur, prev
+	}
+	if prev[len(b)] > k {
+		return k + 1
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// fuzzyDocs - пользователи, у которых в имени есть слово, похожее на word с опечатками
+func (idx *Index) fuzzyDocs(word string) []int {
+	var lists [][]int
+	for _, m := range idx.fuzzy.lookup(fold(word)) {
+		lists = append(lists, idx.fieldPostings(m.term, "name"))
+	}
+	return union(lists)
+}
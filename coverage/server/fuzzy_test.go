@@ -0,0 +1,145 @@
+package server
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"coverage/client"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b   string
+		k      int
+		result int
+	}{
+		{"boyd", "boyd", 2, 0},
+		{"boyd", "boid", 2, 1},
+		{"boyd", "byod", 2, 2},
+		{"boyd", "bo", 2, 2},
+		{"boyd", "wolf", 2, 3},
+		{"hilda", "", 2, 3},
+		{"müller", "muller", 2, 1},
+	}
+	for caseNum, testItem := range tests {
+		result := levenshtein([]rune(testItem.a), []rune(testItem.b), testItem.k)
+		if result != testItem.result {
+			t.Errorf("[%d] %s %s: wrong result, expected %v, got %v", caseNum, testItem.a, testItem.b, testItem.result, result)
+		}
+	}
+}
+
+func TestFuzzyLookupRepeatedBigrams(t *testing.T) {
+	f := newFuzzyIndex([]string{"aaaaaab", "mississippi", "wolf"})
+	tests := map[string][]fuzzyMatch{
+		"aaaaaaa":    {{"aaaaaab", 1}},
+		"misisippi":  {{"mississippi", 2}},
+		"missisippi": {{"mississippi", 1}},
+	}
+	for word, result := range tests {
+		if matches := f.lookup(word); !reflect.DeepEqual(result, matches) {
+			t.Errorf("%s: wrong result, expected %v, got %v", word, result, matches)
+		}
+	}
+}
+
+func TestQueryFuzzy(t *testing.T) {
+	users := []client.User{
+		{Id: 0, Name: "Boyd Wolf", About: "Nulla cillum enim"},
+		{Id: 1, Name: "Hilda Mayer", About: "Boid commodo ex"},
+		{Id: 2, Name: "Brooks Aguilar", About: "Velit ullamco est"},
+		{Id: 3, Name: "Boid Snow", About: "Sunt magna ad"},
+	}
+	store := &Store{users: users, index: NewIndex(users)}
+
+	tests := []struct {
+		query  string
+		fuzzy  bool
+		result []int
+	}{
+		{query: "Boid", result: []int{1, 3}},
+		{query: "Boid", fuzzy: true, result: []int{1, 3, 0}},
+		{query: "Byod", fuzzy: true, result: []int{0, 3}},
+		{query: "Hlda Meyer", fuzzy: true, result: []int{1}},
+		{query: "Brooks", fuzzy: true, result: []int{2}},
+		{query: "ex", fuzzy: true, result: []int{1}},
+		{query: "about:Boyd", fuzzy: true, result: nil},
+		{query: "Wolf NOT Bod", fuzzy: true, result: []int{0}},
+	}
+	for caseNum, testItem := range tests {
+		q, err := ParseQuery(testItem.query)
+		if err != nil {
+			t.Errorf("[%d] expected nil, got error: %v", caseNum, err)
+			continue
+		}
+		q.Fuzzy = testItem.fuzzy
+		var ids []int
+		for _, user := range store.Find(q) {
+			ids = append(ids, user.Id)
+		}
+		if !reflect.DeepEqual(testItem.result, ids) {
+			t.Errorf("[%d] %s: wrong result, expected %v, got %v", caseNum, testItem.query, testItem.result, ids)
+		}
+		// Match не делит на группы, пользователи идут в порядке хранилища
+		sort.Ints(ids)
+		if matched := matchedIds(q, users); !reflect.DeepEqual(ids, matched) {
+			t.Errorf("[%d] %s: Match disagrees with index, expected %v, got %v", caseNum, testItem.query, ids, matched)
+		}
+	}
+
+	q, _ := ParseQuery("Boid")
+	q.Fuzzy = true
+	ranked, _ := store.Rank(q)
+	if ranked[2].Id != 0 || ranked[2].Score <= 0 {
+		t.Errorf("fuzzy match must have positive Score, got %#v", ranked[2])
+	}
+}
+
+func TestFuzzyRequest(t *testing.T) {
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         ts.URL,
+	}
+	result, err := s.FindUsers(client.SearchRequest{
+		Limit:      5,
+		Query:      "Wolff",
+		OrderBy:    1,
+		OrderField: "Name",
+		Fuzzy:      true,
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	if len(result.Users) == 0 || result.Users[0].Name != "Boyd Wolf" {
+		t.Errorf("wrong result, expected Boyd Wolf, got %#v", result.Users)
+	}
+}
+
+func BenchmarkFuzzyBruteForce(b *testing.B) {
+	users := syntheticUsers(100000)
+	word := []rune(fold("Hlda"))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var found []int
+		for pos, user := range users {
+			for _, token := range foldAll(tokenize(user.Name)) {
+				if levenshtein(word, []rune(token), 2) <= 2 {
+					found = append(found, pos)
+					break
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkFuzzyIndex(b *testing.B) {
+	users := syntheticUsers(100000)
+	store := &Store{users: users, index: NewIndex(users)}
+	q, _ := ParseQuery("Hlda")
+	q.Fuzzy = true
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		store.Find(q)
+	}
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"coverage/client"
+)
+
+// Index - обратный индекс: слово -> номера пользователей в Store, где оно встречается.
+// Строится по Name (то есть first_name и last_name) и About, слова хранятся после fold
+type Index struct {
+	terms    []string // отсортированный словарь, по нему ищем префиксы
+	postings map[string][]int
+	freqs    map[string][]termFreq // параллельно postings: сколько раз слово встретилось у пользователя
+
+	// длины полей в словах, нужны для BM25
+	nameLen, aboutLen []int
+	avgName, avgAbout float64
+
+	// похожие слова из имён для поиска с опечатками
+	fuzzy *fuzzyIndex
+}
+
+// termFreq - сколько раз слово встретилось в имени и в about одного пользователя
+type termFreq struct {
+	name, about int
+}
+
+// tokenize режет текст на слова по всему, что не буква и не цифра.
+// Комбинируемые знаки остаются внутри слова, их убирает fold
+func tokenize(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r)
+	})
+}
+
+// NewIndex строит индекс, номера в нём совпадают с индексами в users
+func NewIndex(users []client.User) *Index {
+	idx := &Index{
+		postings: map[string][]int{},
+		freqs:    map[string][]termFreq{},
+		nameLen:  make([]int, len(users)),
+		aboutLen: make([]int, len(users)),
+	}
+	var totalName, totalAbout int
+	names := map[string]bool{}
+	for i, user := range users {
+		freqs := map[string]termFreq{}
+		nameTerms := foldAll(tokenize(user.Name))
+		for _, term := range nameTerms {
+			f := freqs[term]
+			f.name++
+			freqs[term] = f
+			names[term] = true
+		}
+		aboutTerms := foldAll(tokenize(user.About))
+		for _, term := range aboutTerms {
+			f := freqs[term]
+			f.about++
+			freqs[term] = f
+		}
+		// пользователи идут по возрастанию, так что списки остаются отсортированными
+		for term, f := range freqs {
+			idx.postings[term] = append(idx.postings[term], i)
+			idx.freqs[term] = append(idx.freqs[term], f)
+		}
+
+		idx.nameLen[i] = len(nameTerms)
+		idx.aboutLen[i] = len(aboutTerms)
+		totalName += len(nameTerms)
+		totalAbout += len(aboutTerms)
+	}
+	if len(users) > 0 {
+		idx.avgName = float64(totalName) / float64(len(users))
+		idx.avgAbout = float64(totalAbout) / float64(len(users))
+	}
+
+	idx.terms = make([]string, 0, len(idx.postings))
+	for term := range idx.postings {
+		idx.terms = append(idx.terms, term)
+	}
+	sort.Strings(idx.terms)
+
+	nameTerms := make([]string, 0, len(names))
+	for term := range names {
+		nameTerms = append(nameTerms, term)
+	}
+	sort.Strings(nameTerms)
+	idx.fuzzy = newFuzzyIndex(nameTerms)
+	return idx
+}
+
+// fieldPostings - пользователи, у которых слово word (уже после fold) есть в поле field:
+// "name", "about" или "" - в любом
+func (idx *Index) fieldPostings(word, field string) []int {
+	postings := idx.postings[word]
+	if field == "" {
+		return postings
+	}
+
+	freqs := idx.freqs[word]
+	var result []int
+	for j, doc := range postings {
+		if field == "name" && freqs[j].name > 0 || field == "about" && freqs[j].about > 0 {
+			result = append(result, doc)
+		}
+	}
+	return result
+}
+
+// expand - слова словаря, начинающиеся с prefix (уже после fold)
+func (idx *Index) expand(prefix string) []string {
+	start := sort.SearchStrings(idx.terms, prefix)
+	end := start
+	for end < len(idx.terms) && strings.HasPrefix(idx.terms[end], prefix) {
+		end++
+	}
+	return idx.terms[start:end]
+}
+
+func intersect(a, b []int) []int {
+	var result []int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// difference - элементы a, которых нет в b; оба списка отсортированы
+func difference(a, b []int) []int {
+	var result []int
+	for i, j := 0, 0; i < len(a); i++ {
+		for j < len(b) && b[j] < a[i] {
+			j++
+		}
+		if j == len(b) || b[j] != a[i] {
+			result = append(result, a[i])
+		}
+	}
+	return result
+}
+
+func union(lists [][]int) []int {
+	var result []int
+	for _, list := range lists {
+		result = append(result, list...)
+	}
+	sort.Ints(result)
+
+	uniq := result[:0]
+	for i, v := range result {
+		if i == 0 || v != result[i-1] {
+			uniq = append(uniq, v)
+		}
+	}
+	return uniq
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"coverage/client"
+)
+
+type TestCaseIndex struct {
+	query  string
+	result []int
+}
+
+func TestIndexSearch(t *testing.T) {
+	users := []client.User{
+		{Id: 0, Name: "Boyd Wolf", About: "Nulla cillum enim, voluptate."},
+		{Id: 1, Name: "Hilda Mayer", About: "Sit commodo consectetur minim amet ex."},
+		{Id: 2, Name: "Brooks Aguilar", About: "Velit ullamco est aliqua voluptate nisi do."},
+	}
+	store := NewMemoryStore(users)
+
+	tests := []TestCaseIndex{
+		{query: "Boyd", result: []int{0}},
+		{query: "B", result: []int{0, 2}},
+		{query: "volupt", result: []int{0, 2}},
+		{query: "voluptate Wolf", result: []int{0}},
+		{query: "enim, voluptate!", result: []int{0}},
+		{query: "voluptate Hilda", result: nil},
+		{query: "nobody", result: nil},
+		// фраза - целые слова, не префиксы
+		{query: `"volupt"`, result: nil},
+		{query: `"voluptate"`, result: []int{0, 2}},
+	}
+	for caseNum, testItem := range tests {
+		q, err := ParseQuery(testItem.query)
+		if err != nil {
+			t.Errorf("[%d] expected nil, got error: %v", caseNum, err)
+			continue
+		}
+		var ids []int
+		for _, user := range store.Find(q) {
+			ids = append(ids, user.Id)
+		}
+		if !reflect.DeepEqual(testItem.result, ids) {
+			t.Errorf("[%d] wrong result, expected %#v, got %#v", caseNum, testItem.result, ids)
+		}
+	}
+}
+
+var benchWords = strings.Fields("nulla cillum enim voluptate consequat laborum esse excepteur occaecat " +
+	"commodo nostrud cupidatat minim incididunt proident sint pariatur officia anim eiusmod amet " +
+	"deserunt culpa dolore mollit lorem aute dolor aliqua ipsum irure reprehenderit exercitation labore")
+
+var benchNames = strings.Fields("Boyd Wolf Hilda Mayer Brooks Aguilar Allison Valdez Annie Osborn " +
+	"Bell Bauer Whitley Davidson Twila Snow Terrell Hall Kane Sharp Christy Knapp Jennings Mays")
+
+// syntheticUsers генерирует n пользователей со случайными именами и about
+func syntheticUsers(n int) []client.User {
+	rnd := rand.New(rand.NewSource(1))
+	users := make([]client.User, n)
+	for i := range users {
+		about := make([]string, 40)
+		for j := range about {
+			about[j] = benchWords[rnd.Intn(len(benchWords))]
+		}
+		// добавляем редкие слова, чтобы было что искать точечно
+		about = append(about, "word"+strings.Repeat("x", i%7)+string(rune('a'+i%26)))
+		users[i] = client.User{
+			Id:    i,
+			Name:  benchNames[rnd.Intn(len(benchNames))] + " " + benchNames[rnd.Intn(len(benchNames))],
+			About: strings.Join(about, " "),
+		}
+	}
+	return users
+}
+
+var benchQueries = []string{"Boyd", "wordxxxq", "Wolf wordxq", "Hilda voluptate lorem"}
+
+func BenchmarkSearchLinear(b *testing.B) {
+	users := syntheticUsers(100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, query := range benchQueries {
+			var found []int
+			for pos, user := range users {
+				if strings.Contains(user.About, query) || strings.Contains(user.Name, query) {
+					found = append(found, pos)
+				}
+			}
+		}
+	}
+}
+
+// BenchmarkSearchIndex - то же, что делает Server на запрос: разбор запроса и Store.Find
+func BenchmarkSearchIndex(b *testing.B) {
+	store := NewMemoryStore(syntheticUsers(100000))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, query := range benchQueries {
+			q, err := ParseQuery(query)
+			if err != nil {
+				b.Fatal(err)
+			}
+			store.Find(q)
+		}
+	}
+}
+
+func BenchmarkBuildIndex(b *testing.B) {
+	users := syntheticUsers(100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewIndex(users)
+	}
+}
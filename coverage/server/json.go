@@ -0,0 +1,149 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// maxJSONLine - самая длинная строка, которую NewJSONLinesStore готов прочитать
+const maxJSONLine = 1 << 20
+
+// NewJSONStore читает файл с массивом объектов с теми же полями, что у row в dataset.xml:
+// [{"id": 1, "first_name": "Boyd", "last_name": "Wolf", ...}, ...]
+func NewJSONStore(fileName string) (*Store, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, utf8BOM)
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	lines := newLineCounter(data)
+	if token, err := dec.Token(); err != nil || token != json.Delim('[') {
+		return nil, recordError(lines.at(dec.InputOffset()), 0, "expected array of objects")
+	}
+	set := newRowSet()
+	for dec.More() {
+		line := lines.at(nextValue(data, dec.InputOffset()))
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				line = lines.at(syntaxErr.Offset)
+			}
+			return nil, recordError(line, set.next(), "%v", err)
+		}
+		r, err := decodeJSONRow(raw)
+		if err != nil {
+			return nil, recordError(line, set.next(), "%v", err)
+		}
+		if err := set.add(r, line); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, recordError(lines.at(dec.InputOffset()), 0, "%v", err)
+	}
+	return set.store(), nil
+}
+
+// NewJSONLinesStore читает файл, где каждая непустая строка - JSON-объект с теми же полями,
+// что у row в dataset.xml: {"id": 1, "first_name": "Boyd", "last_name": "Wolf", ...}
+func NewJSONLinesStore(fileName string) (*Store, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	set := newRowSet()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLine)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if line == 1 {
+			data = bytes.TrimPrefix(data, utf8BOM)
+		}
+		if len(data) == 0 {
+			continue
+		}
+		r, err := decodeJSONRow(data)
+		if err != nil {
+			return nil, recordError(line, set.next(), "%v", err)
+		}
+		if err := set.add(r, line); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &parseError{err}
+	}
+	return set.store(), nil
+}
+
+// decodeJSONRow разбирает одну запись: объект только из полей rowColumns, id обязателен
+func decodeJSONRow(data []byte) (row, error) {
+	if len(data) == 0 || data[0] != '{' {
+		return row{}, fmt.Errorf("record is not an object")
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return row{}, err
+	}
+	var unknown []string
+	for name := range fields {
+		if _, ok := rowColumns[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return row{}, fmt.Errorf("unknown fields %q", unknown)
+	}
+	if _, ok := fields[requiredColumn]; !ok {
+		return row{}, fmt.Errorf("no %s", requiredColumn)
+	}
+
+	r := row{}
+	err := json.Unmarshal(data, &r)
+	return r, err
+}
+
+// lineCounter - номера строк data по смещениям. Смещения при разборе только растут,
+// так что переводы строк считаются от прошлого смещения, а не с начала файла
+type lineCounter struct {
+	data   []byte
+	offset int64
+	line   int
+}
+
+func newLineCounter(data []byte) *lineCounter {
+	return &lineCounter{data: data, line: 1}
+}
+
+// at - номер строки, на которой стоит offset
+func (l *lineCounter) at(offset int64) int {
+	if offset > int64(len(l.data)) {
+		offset = int64(len(l.data))
+	}
+	if offset < l.offset {
+		l.offset, l.line = 0, 1
+	}
+	l.line += bytes.Count(l.data[l.offset:offset], []byte("\n"))
+	l.offset = offset
+	return l.line
+}
+
+// nextValue пропускает пробелы и запятую перед очередным элементом массива
+func nextValue(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) && bytes.IndexByte([]byte(" \t\r\n,"), data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"coverage/client"
+)
+
+// looksLikeJWT - три части через точку, иначе это обычный токен из файла
+func looksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// JWTKey - ключ подписи. Ключей может быть несколько: новые токены подписываются новым,
+// а выпущенные старым продолжают действовать, пока его не уберут из конфига
+type JWTKey struct {
+	ID     string `json:"kid"`
+	Secret string `json:"secret"`
+}
+
+// JWTConfig - секция "jwt" файла токенов
+type JWTConfig struct {
+	// если задан, iss токена должен совпадать
+	Issuer string `json:"issuer"`
+	// если задана, должна быть среди aud токена
+	Audience string   `json:"audience"`
+	Keys     []JWTKey `json:"keys"`
+	// допустимое расхождение часов при проверке exp и nbf
+	LeewaySeconds int `json:"leeway_seconds"`
+}
+
+// minJWTSecret - короче HMAC-ключ для HS256 брать нельзя
+const minJWTSecret = 32
+
+// JWTVerifier проверяет подписанные токены
+type JWTVerifier struct {
+	config JWTConfig
+	keys   map[string][]byte
+}
+
+// NewJWTVerifier проверяет конфиг: есть ключи, kid не повторяются, секреты не короче minJWTSecret
+func NewJWTVerifier(config JWTConfig) (*JWTVerifier, error) {
+	if len(config.Keys) == 0 {
+		return nil, errors.New("jwt: no keys")
+	}
+	v := &JWTVerifier{config: config, keys: map[string][]byte{}}
+	for _, key := range config.Keys {
+		if _, ok := v.keys[key.ID]; ok {
+			return nil, fmt.Errorf("jwt: duplicate kid %q", key.ID)
+		}
+		if len(key.Secret) < minJWTSecret {
+			return nil, fmt.Errorf("jwt: key %q is shorter than %d bytes", key.ID, minJWTSecret)
+		}
+		v.keys[key.ID] = []byte(key.Secret)
+	}
+	return v, nil
+}
+
+// Verify проверяет подпись, алгоритм, kid, exp, nbf, iss и aud. exp обязателен
+func (v *JWTVerifier) Verify(token string, now time.Time) (*client.JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed jwt", errTokenInvalid)
+	}
+	header := client.JWTHeader{}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	// только HS256, иначе можно подсунуть "none"
+	if header.Algorithm != client.JWTAlgorithm {
+		return nil, fmt.Errorf("%w: unsupported alg %q", errTokenInvalid, header.Algorithm)
+	}
+	secret, ok := v.keys[header.KeyID]
+	if !ok && header.KeyID == "" && len(v.config.Keys) == 1 {
+		// без kid годится только единственный ключ
+		secret, ok = []byte(v.config.Keys[0].Secret), true
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown kid %q", errTokenInvalid, header.KeyID)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, client.JWTSignature(parts[0]+"."+parts[1], secret)) {
+		return nil, fmt.Errorf("%w: bad signature", errTokenInvalid)
+	}
+
+	claims := &client.JWTClaims{}
+	if err := decodeJWTPart(parts[1], claims); err != nil {
+		return nil, err
+	}
+	leeway := int64(v.config.LeewaySeconds)
+	switch {
+	case claims.ExpiresAt == 0:
+		return nil, fmt.Errorf("%w: no exp", errTokenInvalid)
+	case now.Unix() >= claims.ExpiresAt+leeway:
+		return nil, errTokenExpired
+	case claims.NotBefore != 0 && now.Unix() < claims.NotBefore-leeway:
+		return nil, fmt.Errorf("%w: not valid yet", errTokenInvalid)
+	case v.config.Issuer != "" && claims.Issuer != v.config.Issuer:
+		return nil, fmt.Errorf("%w: wrong issuer", errTokenInvalid)
+	case v.config.Audience != "" && !claims.Audience.Contains(v.config.Audience):
+		return nil, fmt.Errorf("%w: wrong audience", errTokenInvalid)
+	}
+	return claims, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return fmt.Errorf("%w: malformed jwt", errTokenInvalid)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: malformed jwt", errTokenInvalid)
+	}
+	return nil
+}
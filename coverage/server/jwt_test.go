@@ -0,0 +1,116 @@
+package server
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"coverage/client"
+)
+
+const (
+	oldJWTSecret = "old-secret-kept-until-tokens-expire"
+	newJWTSecret = "new-secret-used-for-all-new-tokens"
+)
+
+func testVerifier(t *testing.T) *JWTVerifier {
+	v, err := NewJWTVerifier(JWTConfig{
+		Issuer:        "gateway",
+		Audience:      "search",
+		LeewaySeconds: 30,
+		Keys:          []JWTKey{{"2019", oldJWTSecret}, {"2020", newJWTSecret}},
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	return v
+}
+
+func TestJWTVerify(t *testing.T) {
+	v := testVerifier(t)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	claims := func(change func(c *client.JWTClaims)) *client.JWTClaims {
+		c := &client.JWTClaims{
+			Issuer:    "gateway",
+			Audience:  client.Audience{"search"},
+			ExpiresAt: now.Add(time.Minute).Unix(),
+			NotBefore: now.Unix(),
+			Scope:     client.ScopeSearch,
+		}
+		if change != nil {
+			change(c)
+		}
+		return c
+	}
+	sign := func(kid, secret string, c *client.JWTClaims) string {
+		token, err := client.SignJWT(kid, []byte(secret), c)
+		if err != nil {
+			t.Fatalf("expected nil, got error: %v", err)
+		}
+		return token
+	}
+	valid := sign("2020", newJWTSecret, claims(nil))
+	parts := strings.Split(valid, ".")
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"valid", valid, nil},
+		{"old key", sign("2019", oldJWTSecret, claims(nil)), nil},
+		{"aud list", sign("2020", newJWTSecret, claims(func(c *client.JWTClaims) { c.Audience = client.Audience{"ui", "search"} })), nil},
+		{"exp in leeway", sign("2020", newJWTSecret, claims(func(c *client.JWTClaims) { c.ExpiresAt = now.Unix() - 10 })), nil},
+		{"expired", sign("2020", newJWTSecret, claims(func(c *client.JWTClaims) { c.ExpiresAt = now.Unix() - 30 })), errTokenExpired},
+		{"no exp", sign("2020", newJWTSecret, claims(func(c *client.JWTClaims) { c.ExpiresAt = 0 })), errTokenInvalid},
+		{"nbf", sign("2020", newJWTSecret, claims(func(c *client.JWTClaims) { c.NotBefore = now.Unix() + 60 })), errTokenInvalid},
+		{"iss", sign("2020", newJWTSecret, claims(func(c *client.JWTClaims) { c.Issuer = "someone" })), errTokenInvalid},
+		{"aud", sign("2020", newJWTSecret, claims(func(c *client.JWTClaims) { c.Audience = client.Audience{"ui"} })), errTokenInvalid},
+		{"unknown kid", sign("2021", newJWTSecret, claims(nil)), errTokenInvalid},
+		{"wrong key", sign("2019", newJWTSecret, claims(nil)), errTokenInvalid},
+		{"no kid", sign("", newJWTSecret, claims(nil)), errTokenInvalid},
+		{"tampered", parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"exp":9999999999,"scope":"admin"}`)) + "." + parts[2], errTokenInvalid},
+		{"alg none", base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"2020"}`)) + "." + parts[1] + ".", errTokenInvalid},
+		{"garbage", "a.b.c", errTokenInvalid},
+	}
+	for _, testItem := range tests {
+		_, err := v.Verify(testItem.token, now)
+		if testItem.err == nil && err != nil || !errors.Is(err, testItem.err) {
+			t.Errorf("%s: wrong result, expected %v, got %v", testItem.name, testItem.err, err)
+		}
+	}
+
+	// с единственным ключом kid можно не указывать
+	single, _ := NewJWTVerifier(JWTConfig{Keys: []JWTKey{{"", newJWTSecret}}})
+	if _, err := single.Verify(sign("", newJWTSecret, claims(nil)), now); err != nil {
+		t.Errorf("no kid, single key: expected nil, got %v", err)
+	}
+
+	for caseNum, config := range []JWTConfig{
+		{},
+		{Keys: []JWTKey{{"a", "short"}}},
+		{Keys: []JWTKey{{"a", newJWTSecret}, {"a", oldJWTSecret}}},
+	} {
+		if _, err := NewJWTVerifier(config); err == nil {
+			t.Errorf("[%d] expected error, got nil", caseNum)
+		}
+	}
+}
+
+func TestJWTScope(t *testing.T) {
+	store, _ := NewTokenStore(nil)
+	store.SetJWT(testVerifier(t))
+	now := time.Now()
+	minter := &client.JWTMinter{KeyID: "2020", Secret: []byte(newJWTSecret), Issuer: "gateway", Audience: "search", Subject: "ui", Scopes: []string{client.ScopeSearch}}
+	token, _, _ := minter.Mint(now)
+
+	found, err := store.Authenticate(token, client.ScopeSearch, now)
+	if err != nil || found.Name != "ui" {
+		t.Errorf("wrong result, expected token ui, got %v, %v", found, err)
+	}
+	if _, err := store.Authenticate(token, client.ScopeAdmin, now); err != errTokenScope {
+		t.Errorf("wrong result, expected %v, got %v", errTokenScope, err)
+	}
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// diacritics - буква без диакритики для строчных букв с ней
+var diacritics = map[rune]string{}
+
+// ligatures раскрываются в несколько букв
+var ligatures = map[rune]string{
+	'ß': "ss",
+	'æ': "ae",
+	'ǽ': "ae",
+	'œ': "oe",
+	'ĳ': "ij",
+}
+
+// composed - буквы, которые в разложенной форме (NFD) - другая буква и комбинируемый знак,
+// но остаются отдельными буквами: и с бреве - это й, а не и
+var composed = map[[2]rune]rune{
+	{'и', '\u0306'}: 'й',
+	{'у', '\u0306'}: 'ў',
+	{'і', '\u0308'}: 'ї',
+}
+
+func init() {
+	// все строчные буквы Latin-1, Latin Extended-A/B и Latin Extended Additional, которые
+	// в разложенной форме (NFD) - латинская буква и комбинируемые знаки, а в конце строк
+	// буквы со штрихом, у которых разложения нет. й и и - разные буквы, их не смешиваем
+	for base, letters := range map[string]string{
+		"a": "àáâãäåāăąǎǟǡǻȁȃȧḁạảấầẩẫậắằẳẵặ",
+		"b": "ḃḅḇ",
+		"c": "çćĉċčḉ",
+		"d": "ďḋḍḏḑḓđð",
+		"e": "èéêëēĕėęěȅȇȩḕḗḙḛḝẹẻẽếềểễệ",
+		"f": "ḟ",
+		"g": "ĝğġģǧǵḡ",
+		"h": "ĥȟḣḥḧḩḫẖħ",
+		"i": "ìíîïĩīĭįǐȉȋḭḯỉịı",
+		"j": "ĵǰ",
+		"k": "ķǩḱḳḵ",
+		"l": "ĺļľḷḹḻḽŀł",
+		"m": "ḿṁṃ",
+		"n": "ñńņňǹṅṇṉṋŉ",
+		"o": "òóôõöōŏőơǒǫǭȍȏȫȭȯȱṍṏṑṓọỏốồổỗộớờởỡợøǿ",
+		"p": "ṕṗ",
+		"r": "ŕŗřȑȓṙṛṝṟ",
+		"s": "śŝşšșṡṣṥṧṩ",
+		"t": "ţťțṫṭṯṱẗŧ",
+		"u": "ùúûüũūŭůűųưǔǖǘǚǜȕȗṳṵṷṹṻụủứừửữự",
+		"v": "ṽṿ",
+		"w": "ŵẁẃẅẇẉẘ",
+		"x": "ẋẍ",
+		"y": "ýÿŷȳẏẙỳỵỷỹ",
+		"z": "źżžẑẓẕ",
+		"е": "ё",
+	} {
+		for _, r := range letters {
+			diacritics[r] = base
+		}
+	}
+	for r, s := range ligatures {
+		diacritics[r] = s
+	}
+}
+
+// fold приводит слово к виду, в котором оно лежит в индексе:
+// нижний регистр, без диакритики, Boyd, BOYD и Bóyd дают boyd. Это unicode.ToLower,
+// а не полное приведение регистра: σ и ς, например, остаются разными
+func fold(word string) string {
+	b := make([]byte, 0, len(word))
+	// last - предыдущая буква в нижнем регистре, если она записана как есть с позиции lastAt
+	last, lastAt := rune(-1), 0
+	for _, r := range word {
+		if unicode.Is(unicode.Mn, r) {
+			// комбинируемые знаки (ударения в разложенной форме) выбрасываем,
+			// кроме тех, что вместе с буквой дают отдельную букву
+			if c, ok := composed[[2]rune{last, r}]; ok {
+				b = utf8.AppendRune(b[:lastAt], c)
+				last = -1
+			}
+			continue
+		}
+		r = unicode.ToLower(r)
+		if s, ok := diacritics[r]; ok {
+			b = append(b, s...)
+			last = -1
+			continue
+		}
+		last, lastAt = r, len(b)
+		b = utf8.AppendRune(b, r)
+	}
+	return string(b)
+}
+
+func foldAll(words []string) []string {
+	folded := make([]string, len(words))
+	for i, word := range words {
+		folded[i] = fold(word)
+	}
+	return folded
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	"coverage/client"
+)
+
+func TestFold(t *testing.T) {
+	tests := map[string]string{
+		"Boyd":         "boyd",
+		"BOYD":         "boyd",
+		"Bóyd":         "boyd",
+		"Bo\u0301yd":   "boyd",
+		"Çağrı":        "cagri",
+		"Łukasz":       "lukasz",
+		"Straße":       "strasse",
+		"Ærøskøbing":   "aeroskobing",
+		"Ёлка":         "елка",
+		"Ștefan":       "stefan",
+		"Țara":         "tara",
+		"Nguyễn":       "nguyen",
+		"Ǧ":            "g",
+		"Ǿ":            "o",
+		"Май":          "май",
+		"Маи\u0306":    "май",
+		"И\u0306огурт": "йогурт",
+		"Ї\u0301жак":   "їжак",
+		"Ёлка\u0306":   "елка",
+	}
+	for word, result := range tests {
+		if got := fold(word); got != result {
+			t.Errorf("%s: wrong result, expected %q, got %q", word, result, got)
+		}
+	}
+}
+
+func TestQueryCaseSensitive(t *testing.T) {
+	users := []client.User{
+		{Id: 0, Name: "Boyd Wolf", About: "Nulla cillum enim"},
+		{Id: 1, Name: "José Müller", About: "commodo ex"},
+		{Id: 2, Name: "Hilda Mayer", About: "Commodo Ex est"},
+		{Id: 3, Name: "Зоя Иогуртова", About: "Sunt magna ad"},
+		{Id: 4, Name: "Иван Йогуртов", About: "Velit ullamco est"},
+	}
+	store := &Store{users: users, index: NewIndex(users)}
+
+	tests := []struct {
+		query         string
+		caseSensitive bool
+		result        []int
+	}{
+		{query: "boyd", result: []int{0}},
+		{query: "boyd", caseSensitive: true, result: nil},
+		{query: "Boyd", caseSensitive: true, result: []int{0}},
+		{query: "jose muller", result: []int{1}},
+		{query: "JOSÉ", result: []int{1}},
+		{query: "jose", caseSensitive: true, result: nil},
+		{query: "José Mü", caseSensitive: true, result: []int{1}},
+		{query: `"commodo ex"`, result: []int{1, 2}},
+		{query: `"commodo ex"`, caseSensitive: true, result: []int{1}},
+		{query: `"Commodo Ex"`, caseSensitive: true, result: []int{2}},
+		{query: "NOT commodo", caseSensitive: true, result: []int{0, 2, 3, 4}},
+		{query: "и\u0306огурт", result: []int{4}},
+		{query: "иогурт", result: []int{3}},
+	}
+	for caseNum, testItem := range tests {
+		q, err := ParseQuery(testItem.query)
+		if err != nil {
+			t.Errorf("[%d] expected nil, got error: %v", caseNum, err)
+			continue
+		}
+		q.CaseSensitive = testItem.caseSensitive
+		var ids []int
+		for _, user := range store.Find(q) {
+			ids = append(ids, user.Id)
+		}
+		if !reflect.DeepEqual(testItem.result, ids) {
+			t.Errorf("[%d] %s: wrong result, expected %v, got %v", caseNum, testItem.query, testItem.result, ids)
+		}
+		if matched := matchedIds(q, users); !reflect.DeepEqual(testItem.result, matched) {
+			t.Errorf("[%d] %s: Match disagrees with index, expected %v, got %v", caseNum, testItem.query, testItem.result, matched)
+		}
+	}
+}
+
+func TestCaseSensitiveRequest(t *testing.T) {
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         ts.URL,
+	}
+	for caseNum, caseSensitive := range []bool{false, true} {
+		result, err := s.FindUsers(client.SearchRequest{Limit: 1, Query: "boyd", CaseSensitive: caseSensitive})
+		if err != nil {
+			t.Errorf("[%d] expected nil, got error: %v", caseNum, err)
+			continue
+		}
+		if found := len(result.Users) > 0; found == caseSensitive {
+			t.Errorf("[%d] wrong result, got %#v", caseNum, result.Users)
+		}
+	}
+}
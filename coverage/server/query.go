@@ -0,0 +1,524 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"coverage/client"
+)
+
+// Язык запросов в параметре query:
+//
+//	Boyd Wolf                 оба слова (AND между словами можно не писать)
+//	Boyd OR Hilda             любое из слов
+//	NOT commodo               без слова
+//	"commodo ex"              фраза, слова подряд
+//	name:Boyd about:"ex ea"   поиск только в имени или только в about
+//	(Boyd OR Hilda) AND NOT ex
+//
+// Слово ищется как префикс, слова фразы - целиком. AND, OR и NOT - только заглавными.
+// По умолчанию слова сравниваются в нижнем регистре и без диакритики: boyd и BÓYD найдут Boyd.
+// С Fuzzy слово находит ещё и слова из имён, отличающиеся парой опечаток: Bodi найдёт Boyd
+
+// QuerySyntaxError - ошибка разбора запроса, Pos - смещение в байтах от начала query
+type QuerySyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *QuerySyntaxError) Error() string {
+	return fmt.Sprintf("query syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+// Query - разобранный запрос. Пустой запрос подходит всем пользователям.
+// Store ищет по нему в своём индексе, другим хранилищам хватит Match
+type Query struct {
+	text string
+	root queryNode
+	// CaseSensitive - искать слова точно как в запросе, с учётом регистра и диакритики
+	CaseSensitive bool
+	// Fuzzy - находить имена с опечатками, сравнивая их в нижнем регистре
+	Fuzzy bool
+}
+
+// matchMode - как сравнивать слова запроса с текстом
+type matchMode struct {
+	exact bool // с учётом регистра и диакритики
+	fuzzy bool // ещё и с опечатками в имени
+}
+
+// ParseQuery разбирает строку запроса
+func ParseQuery(query string) (*Query, error) {
+	p := &queryParser{lexer: queryLexer{input: query}}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokEOF {
+		return &Query{text: query}, nil
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+	return &Query{text: query, root: root}, nil
+}
+
+// String - запрос, как его передали в ParseQuery
+func (q *Query) String() string {
+	return q.text
+}
+
+// Terms - слова, которые должны найтись (не под NOT), по ним считается релевантность
+func (q *Query) Terms() []string {
+	if q.root == nil {
+		return nil
+	}
+	return q.root.terms(nil)
+}
+
+// Match - подходит ли пользователь под запрос с учётом CaseSensitive и Fuzzy.
+// Находит тех же, что и Store, только перебором, без индекса
+func (q *Query) Match(user *client.User) bool {
+	return q.match(user, true)
+}
+
+// match - Match, где опечатки учитываются, только если fuzzy включён и в самом запросе
+func (q *Query) match(user *client.User, fuzzy bool) bool {
+	if q.root == nil {
+		return true
+	}
+	return q.root.match(user, matchMode{q.CaseSensitive, fuzzy && q.Fuzzy})
+}
+
+// docs - номера подходящих пользователей в Store по возрастанию.
+// fuzzy учитывается, только если он включён и в самом запросе
+func (q *Query) docs(s *Store, fuzzy bool) []int {
+	if q.root == nil {
+		return allDocs(len(s.users))
+	}
+	return q.root.docs(s, matchMode{q.CaseSensitive, fuzzy && q.Fuzzy})
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokPhrase
+	tokField // name: или about:, value - имя поля
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+type queryToken struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func (t queryToken) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokPhrase:
+		return fmt.Sprintf("phrase %q", t.value)
+	case tokField:
+		return fmt.Sprintf("%q", t.value+":")
+	}
+	return fmt.Sprintf("%q", t.value)
+}
+
+// queryFields - поля, по которым можно искать через field:
+var queryFields = map[string]bool{
+	"name":  true,
+	"about": true,
+}
+
+type queryLexer struct {
+	input string
+	pos   int
+}
+
+func (l *queryLexer) next() (queryToken, error) {
+	for l.pos < len(l.input) && (l.input[l.pos] == ' ' || l.input[l.pos] == '\t') {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos == len(l.input) {
+		return queryToken{tokEOF, "", start}, nil
+	}
+
+	switch l.input[l.pos] {
+	case '(':
+		l.pos++
+		return queryToken{tokLParen, "(", start}, nil
+	case ')':
+		l.pos++
+		return queryToken{tokRParen, ")", start}, nil
+	case '"':
+		end := strings.IndexByte(l.input[start+1:], '"')
+		if end < 0 {
+			return queryToken{}, &QuerySyntaxError{start, "unterminated phrase"}
+		}
+		l.pos = start + 1 + end + 1
+		return queryToken{tokPhrase, l.input[start+1 : start+1+end], start}, nil
+	}
+
+	for l.pos < len(l.input) && !strings.ContainsRune(" \t()\":", rune(l.input[l.pos])) {
+		l.pos++
+	}
+	word := l.input[start:l.pos]
+	if word == "" {
+		return queryToken{}, &QuerySyntaxError{start, "unexpected \":\""}
+	}
+	if l.pos < len(l.input) && l.input[l.pos] == ':' {
+		if !queryFields[word] {
+			return queryToken{}, &QuerySyntaxError{start, fmt.Sprintf("unknown field %q", word)}
+		}
+		l.pos++
+		return queryToken{tokField, word, start}, nil
+	}
+
+	switch word {
+	case "AND":
+		return queryToken{tokAnd, word, start}, nil
+	case "OR":
+		return queryToken{tokOr, word, start}, nil
+	case "NOT":
+		return queryToken{tokNot, word, start}, nil
+	}
+	return queryToken{tokWord, word, start}, nil
+}
+
+type queryParser struct {
+	lexer queryLexer
+	tok   queryToken
+}
+
+func (p *queryParser) next() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *queryParser) errorf(format string, args ...interface{}) error {
+	return &QuerySyntaxError{p.tok.pos, fmt.Sprintf(format, args...)}
+}
+
+// or := and ("OR" and)*
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOr {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left, right}
+	}
+	return left, nil
+}
+
+// and := not (["AND"] not)*
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.tok.kind {
+		case tokAnd:
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		case tokWord, tokPhrase, tokField, tokNot, tokLParen:
+			// AND без явного оператора
+		default:
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left, right}
+	}
+}
+
+// not := "NOT" not | primary
+func (p *queryParser) parseNot() (queryNode, error) {
+	if p.tok.kind != tokNot {
+		return p.parsePrimary()
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	child, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return &notNode{child}, nil
+}
+
+// primary := "(" or ")" | [field ":"] (word | phrase)
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	switch p.tok.kind {
+	case tokLParen:
+		open := p.tok
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokRParen {
+			return nil, p.errorf("empty parentheses")
+		}
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, &QuerySyntaxError{open.pos, "unclosed parenthesis"}
+		}
+		return node, p.next()
+	case tokField:
+		field := p.tok.value
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokWord && p.tok.kind != tokPhrase {
+			return nil, p.errorf("expected word or phrase after %q, got %s", field+":", p.tok)
+		}
+		return p.parseTerm(field)
+	case tokWord, tokPhrase:
+		return p.parseTerm("")
+	}
+	return nil, p.errorf("unexpected %s", p.tok)
+}
+
+func (p *queryParser) parseTerm(field string) (queryNode, error) {
+	tok := p.tok
+	words := tokenize(tok.value)
+	if len(words) == 0 {
+		return nil, p.errorf("%s has no letters or digits", tok)
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if tok.kind == tokWord && len(words) == 1 {
+		return &termNode{field, words[0]}, nil
+	}
+	// слово с дефисом и т.п. ищем как фразу
+	return &phraseNode{field, words}, nil
+}
+
+type queryNode interface {
+	// docs ищет кандидатов по индексу, а при mode.exact ещё и сверяет их с исходным текстом
+	docs(s *Store, mode matchMode) []int
+	// match проверяет одного пользователя по тексту, результат тот же, что у docs
+	match(user *client.User, mode matchMode) bool
+	terms(dst []string) []string
+}
+
+type termNode struct {
+	field string // "" - и имя, и about
+	word  string
+}
+
+type phraseNode struct {
+	field string
+	words []string
+}
+
+type andNode struct {
+	left, right queryNode
+}
+
+type orNode struct {
+	left, right queryNode
+}
+
+type notNode struct {
+	child queryNode
+}
+
+func (n *termNode) docs(s *Store, mode matchMode) []int {
+	var lists [][]int
+	for _, term := range s.index.expand(fold(n.word)) {
+		lists = append(lists, s.index.fieldPostings(term, n.field))
+	}
+	result := union(lists)
+	if mode.exact {
+		var exact []int
+		for _, doc := range result {
+			if n.matchExact(&s.users[doc]) {
+				exact = append(exact, doc)
+			}
+		}
+		result = exact
+	}
+	if mode.fuzzy && n.field != "about" {
+		result = union([][]int{result, s.index.fuzzyDocs(n.word)})
+	}
+	return result
+}
+
+func (n *termNode) matchExact(user *client.User) bool {
+	for _, text := range fieldTexts(user, n.field) {
+		for _, token := range tokenize(text) {
+			if strings.HasPrefix(token, n.word) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (n *termNode) match(user *client.User, mode matchMode) bool {
+	if mode.exact && n.matchExact(user) || !mode.exact && n.matchFolded(user) {
+		return true
+	}
+	return mode.fuzzy && n.field != "about" && n.matchFuzzy(user)
+}
+
+func (n *termNode) matchFolded(user *client.User) bool {
+	prefix := fold(n.word)
+	for _, text := range fieldTexts(user, n.field) {
+		for _, token := range tokenize(text) {
+			if strings.HasPrefix(fold(token), prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchFuzzy - есть ли в имени слово, отличающееся от n.word опечатками, как в fuzzyIndex.lookup
+func (n *termNode) matchFuzzy(user *client.User) bool {
+	word := []rune(fold(n.word))
+	k := maxTypos(word)
+	if k == 0 {
+		return false
+	}
+	for _, token := range tokenize(user.Name) {
+		if d := levenshtein(word, []rune(fold(token)), k); d > 0 && d <= k {
+			return true
+		}
+	}
+	return false
+}
+
+// фраза ищется без опечаток
+func (n *phraseNode) docs(s *Store, mode matchMode) []int {
+	// кандидаты - у кого есть все слова фразы, порядок проверяем по самому тексту
+	folded := foldAll(n.words)
+	candidates := s.index.fieldPostings(folded[0], n.field)
+	for _, word := range folded[1:] {
+		candidates = intersect(candidates, s.index.fieldPostings(word, n.field))
+	}
+
+	var result []int
+	for _, doc := range candidates {
+		if n.match(&s.users[doc], mode) {
+			result = append(result, doc)
+		}
+	}
+	return result
+}
+
+func (n *phraseNode) match(user *client.User, mode matchMode) bool {
+	words := n.words
+	if !mode.exact {
+		words = foldAll(words)
+	}
+	for _, text := range fieldTexts(user, n.field) {
+		tokens := tokenize(text)
+		if !mode.exact {
+			tokens = foldAll(tokens)
+		}
+		if containsPhrase(tokens, words) {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldTexts - тексты, в которых ищется слово с полем field
+func fieldTexts(user *client.User, field string) []string {
+	switch field {
+	case "name":
+		return []string{user.Name}
+	case "about":
+		return []string{user.About}
+	}
+	return []string{user.Name, user.About}
+}
+
+func containsPhrase(tokens, words []string) bool {
+	for i := 0; i+len(words) <= len(tokens); i++ {
+		match := true
+		for j, word := range words {
+			if tokens[i+j] != word {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *andNode) docs(s *Store, mode matchMode) []int {
+	return intersect(n.left.docs(s, mode), n.right.docs(s, mode))
+}
+
+func (n *orNode) docs(s *Store, mode matchMode) []int {
+	return union([][]int{n.left.docs(s, mode), n.right.docs(s, mode)})
+}
+
+// под NOT опечатки не учитываются, иначе с Fuzzy находилось бы меньше, чем без него
+func (n *notNode) docs(s *Store, mode matchMode) []int {
+	mode.fuzzy = false
+	return difference(allDocs(len(s.users)), n.child.docs(s, mode))
+}
+
+func (n *andNode) match(user *client.User, mode matchMode) bool {
+	return n.left.match(user, mode) && n.right.match(user, mode)
+}
+
+func (n *orNode) match(user *client.User, mode matchMode) bool {
+	return n.left.match(user, mode) || n.right.match(user, mode)
+}
+
+func (n *notNode) match(user *client.User, mode matchMode) bool {
+	mode.fuzzy = false
+	return !n.child.match(user, mode)
+}
+
+func (n *termNode) terms(dst []string) []string   { return append(dst, n.word) }
+func (n *phraseNode) terms(dst []string) []string { return append(dst, n.words...) }
+func (n *andNode) terms(dst []string) []string    { return n.right.terms(n.left.terms(dst)) }
+func (n *orNode) terms(dst []string) []string     { return n.right.terms(n.left.terms(dst)) }
+func (n *notNode) terms(dst []string) []string    { return dst }
+
+func allDocs(n int) []int {
+	docs := make([]int, n)
+	for i := range docs {
+		docs[i] = i
+	}
+	return docs
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"coverage/client"
+)
+
+func TestQueryEval(t *testing.T) {
+	users := []client.User{
+		{Id: 0, Name: "Boyd Wolf", About: "Nulla cillum enim, commodo ex voluptate."},
+		{Id: 1, Name: "Hilda Mayer", About: "Sit commodo consectetur minim amet ex."},
+		{Id: 2, Name: "Brooks Aguilar", About: "Velit ullamco est aliqua voluptate nisi do. Boyd"},
+		{Id: 3, Name: "Commodo Exeter", About: "Sunt magna ad excepteur eu sint."},
+	}
+	store := &Store{users: users, index: NewIndex(users)}
+
+	tests := []TestCaseIndex{
+		{query: "", result: []int{0, 1, 2, 3}},
+		{query: "Boyd", result: []int{0, 2}},
+		{query: "name:Boyd", result: []int{0}},
+		{query: "about:Boyd", result: []int{2}},
+		{query: "Boyd Wolf", result: []int{0}},
+		{query: "Boyd AND Wolf", result: []int{0}},
+		{query: "Boyd OR Hilda", result: []int{0, 1, 2}},
+		{query: "NOT Boyd", result: []int{1, 3}},
+		{query: "voluptate AND NOT (Boyd OR Hilda)", result: nil},
+		{query: "(Boyd OR Hilda) commodo", result: []int{0, 1}},
+		{query: `"commodo ex"`, result: []int{0}},
+		{query: `about:"amet ex"`, result: []int{1}},
+		{query: `name:"Commodo Exeter"`, result: []int{3}},
+		{query: `name:"Commodo Ex"`, result: nil},
+		{query: "name:Commodo OR about:Velit", result: []int{2, 3}},
+		{query: "NOT NOT Brooks", result: []int{2}},
+		{query: "commodo-ex", result: []int{0}},
+	}
+	for caseNum, testItem := range tests {
+		q, err := ParseQuery(testItem.query)
+		if err != nil {
+			t.Errorf("[%d] expected nil, got error: %v", caseNum, err)
+			continue
+		}
+		var ids []int
+		for _, user := range store.Find(q) {
+			ids = append(ids, user.Id)
+		}
+		if !reflect.DeepEqual(testItem.result, ids) {
+			t.Errorf("[%d] %s: wrong result, expected %v, got %v", caseNum, testItem.query, testItem.result, ids)
+		}
+		if matched := matchedIds(q, users); !reflect.DeepEqual(testItem.result, matched) {
+			t.Errorf("[%d] %s: Match disagrees with index, expected %v, got %v", caseNum, testItem.query, testItem.result, matched)
+		}
+	}
+}
+
+// matchedIds - Id пользователей, для которых q.Match, так ищет хранилище без индекса
+func matchedIds(q *Query, users []client.User) []int {
+	var ids []int
+	for i := range users {
+		if q.Match(&users[i]) {
+			ids = append(ids, users[i].Id)
+		}
+	}
+	return ids
+}
+
+type TestCaseQueryError struct {
+	query string
+	pos   int
+	msg   string
+}
+
+func TestQuerySyntaxError(t *testing.T) {
+	tests := []TestCaseQueryError{
+		{query: `Boyd "commodo ex`, pos: 5, msg: "unterminated phrase"},
+		{query: "(Boyd OR Hilda", pos: 0, msg: "unclosed parenthesis"},
+		{query: "Boyd OR", pos: 7, msg: "unexpected end of query"},
+		{query: "Boyd )", pos: 5, msg: `unexpected ")"`},
+		{query: "email:Boyd", pos: 0, msg: `unknown field "email"`},
+		{query: "name:)", pos: 5, msg: `expected word or phrase after "name:"`},
+		{query: "Boyd AND ()", pos: 10, msg: "empty parentheses"},
+		{query: "Boyd :x", pos: 5, msg: `unexpected ":"`},
+		{query: "Boyd ...", pos: 5, msg: "has no letters or digits"},
+		{query: "NOT", pos: 3, msg: "unexpected end of query"},
+	}
+	for caseNum, testItem := range tests {
+		_, err := ParseQuery(testItem.query)
+		syntaxErr, ok := err.(*QuerySyntaxError)
+		if !ok {
+			t.Errorf("[%d] expected QuerySyntaxError, got %#v", caseNum, err)
+			continue
+		}
+		if syntaxErr.Pos != testItem.pos || !strings.Contains(syntaxErr.Msg, testItem.msg) {
+			t.Errorf("[%d] %s: wrong result, expected %q at %d, got %v", caseNum, testItem.query, testItem.msg, testItem.pos, err)
+		}
+	}
+}
+
+func TestQuerySyntaxErrorResponse(t *testing.T) {
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         ts.URL,
+	}
+	_, err := s.FindUsers(client.SearchRequest{Query: "Boyd AND (Wolf"})
+	if err == nil || !strings.Contains(err.Error(), "query syntax error at position 9: unclosed parenthesis") {
+		t.Errorf("wrong result, got %v", err)
+	}
+}
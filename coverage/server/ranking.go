@@ -0,0 +1,82 @@
+package server
+
+import (
+	"math"
+
+	"coverage/client"
+)
+
+// OrderFieldRelevance - сортировка по релевантности запросу (BM25).
+// С client.OrderByDesc самые релевантные идут первыми, с client.OrderByAsc - последними
+const OrderFieldRelevance = "Relevance"
+
+// параметры BM25, значения по умолчанию из литературы
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+	// попадание в имя весит больше, чем попадание в about
+	nameBoost = 2.0
+)
+
+// Scores считает BM25 по about плюс BM25 по имени с весом nameBoost
+// для пользователей docs (отсортированных по возрастанию).
+// Слово запроса, как и в Search, считается префиксом: вклад дают все его продолжения
+func (idx *Index) Scores(terms []string, docs []int) []float64 {
+	scores := make([]float64, len(docs))
+	for _, prefix := range terms {
+		for _, term := range idx.expand(fold(prefix)) {
+			idx.addScores(term, 1, docs, scores)
+		}
+	}
+	return scores
+}
+
+// addFuzzyScores добавляет к scores вклад слов из имён, похожих на terms с опечатками,
+// с весом fuzzyWeight, делённым на число опечаток
+func (idx *Index) addFuzzyScores(terms []string, docs []int, scores []float64) {
+	for _, word := range terms {
+		for _, m := range idx.fuzzy.lookup(fold(word)) {
+			idx.addScores(m.term, fuzzyWeight/float64(m.distance), docs, scores)
+		}
+	}
+}
+
+func (idx *Index) addScores(term string, weight float64, docs []int, scores []float64) {
+	postings, freqs := idx.postings[term], idx.freqs[term]
+	n := float64(len(idx.nameLen))
+	df := float64(len(postings))
+	idf := weight * math.Log(1+(n-df+0.5)/(df+0.5))
+
+	// и docs, и postings отсортированы, идём по ним одновременно
+	for i, j := 0, 0; i < len(docs) && j < len(postings); {
+		switch {
+		case docs[i] < postings[j]:
+			i++
+		case docs[i] > postings[j]:
+			j++
+		default:
+			doc, f := docs[i], freqs[j]
+			scores[i] += idf * bm25(f.about, idx.aboutLen[doc], idx.avgAbout)
+			scores[i] += idf * nameBoost * bm25(f.name, idx.nameLen[doc], idx.avgName)
+			i++
+			j++
+		}
+	}
+}
+
+func bm25(tf, length int, avgLength float64) float64 {
+	if tf == 0 {
+		return 0
+	}
+	norm := 1.0
+	if avgLength > 0 {
+		norm = 1 - bm25B + bm25B*float64(length)/avgLength
+	}
+	return float64(tf) * (bm25K1 + 1) / (float64(tf) + bm25K1*norm)
+}
+
+// Rank - то же, что Find, но у каждого пользователя заполнен Score.
+// Порядок остаётся порядком файла, сортирует вызывающий
+func (s *Store) Rank(query *Query) ([]client.User, error) {
+	return s.search(query, true), nil
+}
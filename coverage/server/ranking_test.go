@@ -0,0 +1,67 @@
+package server
+
+import (
+	"testing"
+
+	"coverage/client"
+)
+
+func TestRankNameBoost(t *testing.T) {
+	users := []client.User{
+		{Id: 0, Name: "Hilda Mayer", About: "Boyd and Hilda are friends"},
+		{Id: 1, Name: "Boyd Wolf", About: "Sit commodo consectetur"},
+		{Id: 2, Name: "Brooks Aguilar", About: "Velit ullamco est"},
+	}
+	store := &Store{users: users, index: NewIndex(users)}
+
+	query, _ := ParseQuery("Boyd")
+	result, _ := store.Rank(query)
+	if len(result) != 2 {
+		t.Fatalf("wrong result, expected 2 users, got %#v", result)
+	}
+	if result[1].Score <= result[0].Score {
+		t.Errorf("name hit must score higher than about hit, got %v and %v", result[1].Score, result[0].Score)
+	}
+	if users[0].Score != 0 {
+		t.Errorf("Rank must not change Store")
+	}
+}
+
+func TestOrderFieldRelevance(t *testing.T) {
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         ts.URL,
+	}
+	result, err := s.FindUsers(client.SearchRequest{
+		Limit:      5,
+		Query:      "Boyd",
+		OrderBy:    client.OrderByDesc,
+		OrderField: OrderFieldRelevance,
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	if len(result.Users) == 0 || result.Users[0].Name != "Boyd Wolf" {
+		t.Fatalf("wrong result, expected Boyd Wolf first, got %#v", result.Users)
+	}
+	for i, user := range result.Users {
+		if user.Score <= 0 {
+			t.Errorf("[%d] expected positive Score, got %v", i, user.Score)
+		}
+		if i > 0 && user.Score > result.Users[i-1].Score {
+			t.Errorf("[%d] users must be sorted by Score desc", i)
+		}
+	}
+	asc, err := s.FindUsers(client.SearchRequest{
+		Limit:      25,
+		Query:      "Boyd",
+		OrderBy:    client.OrderByAsc,
+		OrderField: OrderFieldRelevance,
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	if last := asc.Users[len(asc.Users)-1]; last.Name != "Boyd Wolf" {
+		t.Errorf("wrong result, expected Boyd Wolf last with OrderByAsc, got %#v", asc.Users)
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit - квота токена: в среднем Rate запросов в секунду, подряд не больше Burst
+type RateLimit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// validate - квота без ограничений (nil) или с положительными Rate и Burst
+func (l *RateLimit) validate() error {
+	if l != nil && (l.Rate <= 0 || l.Burst < 1) {
+		return fmt.Errorf("bad rate_limit: rate must be > 0 and burst >= 1")
+	}
+	return nil
+}
+
+// sweepEvery - раз во сколько запросов выбрасывать полностью восстановившиеся корзины
+const sweepEvery = 1024
+
+// rateLimiter - token bucket на каждый ключ
+type rateLimiter struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	takes   int
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  RateLimit
+}
+
+// rateDecision - результат проверки квоты, из него собираются заголовки X-RateLimit-*
+type rateDecision struct {
+	allowed    bool
+	limit      int
+	remaining  int
+	reset      time.Duration // через сколько корзина снова будет полной
+	retryAfter time.Duration // через сколько появится токен, если запрос отвергнут
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{now: time.Now, buckets: map[string]*bucket{}}
+}
+
+// take берёт из корзины key один токен. limit передаётся каждый раз,
+// чтобы после перечитывания файла токенов действовала новая квота
+func (l *rateLimiter) take(key string, limit RateLimit) rateDecision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.takes++
+	if l.takes%sweepEvery == 0 {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.limit = limit
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	d := rateDecision{limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.allowed = true
+	} else {
+		d.retryAfter = time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	}
+	d.remaining = int(b.tokens)
+	d.reset = time.Duration((float64(limit.Burst) - b.tokens) / limit.Rate * float64(time.Second))
+	return d
+}
+
+// sweep удаляет корзины, которые успели наполниться: для них ничего не помним
+func (l *rateLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// limitRate проверяет квоту токена и пишет заголовки X-RateLimit-*.
+// Если квота исчерпана, отвечает 429 и возвращает false
+func (s *Server) limitRate(w http.ResponseWriter, key string, limit *RateLimit) bool {
+	if limit == nil {
+		return true
+	}
+
+	d := s.limiter.take(key, *limit)
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(d.limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(d.remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(d.reset)))
+	if d.allowed {
+		return true
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(d.retryAfter)))
+	w.WriteHeader(http.StatusTooManyRequests)
+	writeError(w, "rate limit exceeded")
+	return false
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
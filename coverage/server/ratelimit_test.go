@@ -0,0 +1,100 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"coverage/client"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter()
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+	limit := RateLimit{Rate: 2, Burst: 3}
+
+	tests := []struct {
+		advance    time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}{
+		{0, true, 2, 0},
+		{0, true, 1, 0},
+		{0, true, 0, 0},
+		{0, false, 0, 500 * time.Millisecond},
+		{250 * time.Millisecond, false, 0, 250 * time.Millisecond},
+		{250 * time.Millisecond, true, 0, 0},
+		{time.Second, true, 1, 0},
+	}
+	for caseNum, testItem := range tests {
+		now = now.Add(testItem.advance)
+		d := l.take("a", limit)
+		if d.allowed != testItem.allowed || d.remaining != testItem.remaining || d.retryAfter != testItem.retryAfter {
+			t.Errorf("[%d] wrong result, got %+v", caseNum, d)
+		}
+	}
+
+	// другие ключи квоту не тратят
+	if d := l.take("b", limit); !d.allowed || d.remaining != 2 {
+		t.Errorf("wrong result, expected fresh bucket, got %+v", d)
+	}
+
+	now = now.Add(time.Hour)
+	l.sweep(now)
+	if len(l.buckets) != 0 {
+		t.Errorf("wrong result, expected full buckets to be swept, got %d", len(l.buckets))
+	}
+}
+
+func TestRateLimitResponse(t *testing.T) {
+	// у каждого Server свои квоты, эти не трогают квоты ts
+	limitedTs := httptest.NewServer(New(NewXMLFileStore(testDataset), testTokens, Options{}))
+	defer limitedTs.Close()
+	s := &client.SearchClient{
+		AccessToken: "LimitedToken",
+		URL:         limitedTs.URL,
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := s.FindUsers(client.SearchRequest{Limit: 1}); err != nil {
+			t.Fatalf("[%d] expected nil, got error: %v", i, err)
+		}
+	}
+
+	_, err := s.FindUsers(client.SearchRequest{Limit: 1})
+	var searchErr *client.SearchError
+	if !errors.Is(err, client.ErrRateLimited) || !errors.As(err, &searchErr) || searchErr.RetryAfter != 2*time.Second {
+		t.Errorf("wrong result, expected %v with Retry-After 2s, got %v", client.ErrRateLimited, err)
+	}
+
+	req, _ := http.NewRequest("GET", limitedTs.URL+"?limit=1&offset=0&order_by=0", nil)
+	req.Header.Set("AccessToken", "LimitedToken")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	resp.Body.Close()
+	headers := map[string]string{
+		"Retry-After":           "2",
+		"X-Ratelimit-Limit":     "2",
+		"X-Ratelimit-Remaining": "0",
+		"X-Ratelimit-Reset":     "4",
+	}
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("wrong result, expected 429, got %d", resp.StatusCode)
+	}
+	for name, value := range headers {
+		if got := resp.Header.Get(name); got != value {
+			t.Errorf("%s: wrong result, expected %s, got %s", name, value, got)
+		}
+	}
+
+	// у остальных токенов своя квота
+	s.AccessToken = token
+	if _, err := s.FindUsers(client.SearchRequest{Limit: 1}); err != nil {
+		t.Errorf("expected nil, got error: %v", err)
+	}
+}
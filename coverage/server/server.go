@@ -0,0 +1,356 @@
+// Package server - SearchServer: поиск пользователей по HTTP поверх подключаемого UserStore
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"coverage/client"
+)
+
+type row struct {
+	ID            int    `xml:"id" json:"id"`
+	GUID          string `xml:"guid" json:"guid"`
+	IsActive      bool   `xml:"isActive" json:"isActive"`
+	Balance       string `xml:"balance" json:"balance"`
+	Picture       string `xml:"picture" json:"picture"`
+	Age           int    `xml:"age" json:"age"`
+	EyeColor      string `xml:"eyeColor" json:"eyeColor"`
+	FirstName     string `xml:"first_name" json:"first_name"`
+	LastName      string `xml:"last_name" json:"last_name"`
+	Gender        string `xml:"gender" json:"gender"`
+	Company       string `xml:"company" json:"company"`
+	Email         string `xml:"email" json:"email"`
+	Phone         string `xml:"phone" json:"phone"`
+	Address       string `xml:"address" json:"address"`
+	About         string `xml:"about" json:"about"`
+	Registered    string `xml:"registered" json:"registered"`
+	FavoriteFruit string `xml:"favoriteFruit" json:"favoriteFruit"`
+}
+
+// reloader - UserStore, который умеет перечитывать данные по запросу, см. Server.Reload
+type reloader interface {
+	Reload() (bool, error)
+}
+
+// Options - необязательные настройки Server, нулевое значение годится
+type Options struct {
+	// ключ, которым подписываются курсоры. Пустой - случайный, тогда курсоры
+	// не переживают перезапуск и не подходят другим копиям сервера
+	CursorSecret []byte
+	// как часто проверять, не изменился ли файл токенов; 0 - DefaultReloadInterval,
+	// отрицательное - не проверять
+	TokensReloadInterval time.Duration
+}
+
+// Server - SearchServer поверх UserStore. Всё его состояние - токены, квоты, ключ курсоров -
+// своё, несколько Server в одном процессе друг другу не мешают
+type Server struct {
+	store        UserStore
+	tokens       *tokenCache
+	limiter      *rateLimiter
+	cursorSecret []byte
+	mux          *http.ServeMux
+	stopTokens   func()
+}
+
+// New собирает сервер: поиск на "/" и служебная перезагрузка данных на "/admin/reload".
+// Токены доступа читаются из tokensFile, см. LoadTokens, и перечитываются в фоне до Close
+func New(store UserStore, tokensFile string, opts Options) *Server {
+	secret := opts.CursorSecret
+	if len(secret) == 0 {
+		secret = randomSecret()
+	}
+	s := &Server{
+		store:        store,
+		tokens:       &tokenCache{fileName: tokensFile},
+		limiter:      newRateLimiter(),
+		cursorSecret: secret,
+		mux:          http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/", s.Search)
+	s.mux.HandleFunc("/admin/reload", s.Reload)
+
+	interval := opts.TokensReloadInterval
+	if interval == 0 {
+		interval = DefaultReloadInterval
+	}
+	s.stopTokens = func() {}
+	if interval > 0 {
+		s.stopTokens = s.tokens.watch(interval)
+	}
+	return s
+}
+
+// Close перестаёт следить за файлом токенов. UserStore сервер не закрывает, он не его
+func (s *Server) Close() {
+	s.stopTokens()
+}
+
+// ServeHTTP отдаёт запрос нужной ручке
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Search ищет пользователей, параметры запроса см. SearchClient.FindUsers
+func (s *Server) Search(w http.ResponseWriter, r *http.Request) {
+	began := time.Now()
+
+	if !s.authorize(w, r, client.ScopeSearch) {
+		return
+	}
+
+	limit, err := strconv.Atoi(r.FormValue("limit"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest) //StatusBadRequest
+		io.WriteString(w, `{"error": "no limit in request"}`)
+		return
+	}
+	// FindUsers шлёт в limit на запись больше для старых серверов, а размер страницы - в page_limit
+	if param := r.FormValue("page_limit"); param != "" {
+		if limit, err = strconv.Atoi(param); err != nil {
+			w.WriteHeader(http.StatusBadRequest) //StatusBadRequest
+			io.WriteString(w, `{"error": "bad page_limit in request"}`)
+			return
+		}
+	}
+	if limit < 0 {
+		w.WriteHeader(http.StatusBadRequest) //StatusBadRequest
+		io.WriteString(w, `{"error": "limit must not be negative"}`)
+		return
+	}
+
+	offset, err := strconv.Atoi(r.FormValue("offset"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest) //StatusBadRequest
+		io.WriteString(w, `{"error": "no offset in request"}`)
+		return
+	}
+	if offset < 0 {
+		w.WriteHeader(http.StatusBadRequest) //StatusBadRequest
+		io.WriteString(w, `{"error": "offset must not be negative"}`)
+		return
+	}
+
+	orderBy, err := strconv.Atoi(r.FormValue("order_by"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest) //StatusBadRequest
+		io.WriteString(w, `{"error": "no order_by in request"}`)
+		return
+	}
+
+	query := r.FormValue("query")
+	orderField := r.FormValue("order_field")
+
+	fields, err := parseFields(r.FormValue("fields"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest) //StatusBadRequest
+		writeError(w, err.Error())
+		return
+	}
+
+	caseSensitive := false
+	if param := r.FormValue("case_sensitive"); param != "" {
+		caseSensitive, err = strconv.ParseBool(param)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest) //StatusBadRequest
+			io.WriteString(w, `{"error": "bad case_sensitive in request"}`)
+			return
+		}
+	}
+
+	fuzzy := false
+	if param := r.FormValue("fuzzy"); param != "" {
+		fuzzy, err = strconv.ParseBool(param)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest) //StatusBadRequest
+			io.WriteString(w, `{"error": "bad fuzzy in request"}`)
+			return
+		}
+	}
+
+	sortParam, err := parseSort(r.FormValue("sort"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest) //StatusBadRequest
+		writeError(w, err.Error())
+		return
+	}
+
+	cursorParam := r.FormValue("cursor")
+
+	var filters []*fieldFilter
+	for _, param := range r.Form["filter"] {
+		f, err := parseFilter(param)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest) //StatusBadRequest
+			writeError(w, err.Error())
+			return
+		}
+		filters = append(filters, f)
+	}
+
+	req := client.SearchRequest{
+		Limit:         limit,
+		Offset:        offset,
+		Query:         query,
+		OrderField:    orderField,
+		OrderBy:       orderBy,
+		Fields:        fields,
+		CaseSensitive: caseSensitive,
+		Fuzzy:         fuzzy,
+		Sort:          sortParam,
+	}
+
+	q, err := ParseQuery(req.Query)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest) //StatusBadRequest
+		writeError(w, err.Error())
+		return
+	}
+	q.CaseSensitive = req.CaseSensitive
+	q.Fuzzy = req.Fuzzy
+
+	keys, err := sortKeys(req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest) //StatusBadRequest
+		writeError(w, err.Error())
+		return
+	}
+
+	var found []client.User
+	if store, ok := s.store.(ranker); ok && needsScore(keys) {
+		found, err = store.Rank(q)
+	} else {
+		found, err = s.store.Search(q)
+	}
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	// found теперь наш, см. UserStore.Search: фильтры, сортировка и project меняют его на месте
+	users := applyFilters(found, filters)
+
+	var isExact map[int]bool
+	if req.Fuzzy {
+		// найденные без опечаток всегда выше, сортировка идёт внутри групп
+		isExact = make(map[int]bool, len(users))
+		exactUsers := make([]client.User, 0, len(users))
+		var fuzzyUsers []client.User
+		for _, user := range users {
+			if q.match(&user, false) {
+				isExact[user.Id] = true
+				exactUsers = append(exactUsers, user)
+			} else {
+				fuzzyUsers = append(fuzzyUsers, user)
+			}
+		}
+		users = append(exactUsers, fuzzyUsers...)
+	}
+	// без ключей отдаём как встретилось; курсору же нужен полный порядок, там сортируем по Id
+	ordered := len(keys) > 0 || cursorParam != ""
+	plan := newSortPlan(keys, isExact)
+	if ordered {
+		plan.sort(users)
+	}
+
+	start := req.Offset
+	if cursorParam != "" {
+		c, err := decodeCursor(cursorParam, plan, s.cursorSecret)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest) //StatusBadRequest
+			writeError(w, err.Error())
+			return
+		}
+		start = c.start(plan, users, req.Limit)
+	}
+	if start > len(users) {
+		start = len(users)
+	}
+	// сравниваем до сложения: start + огромный limit переполнил бы int
+	end := len(users)
+	if req.Limit < end-start {
+		end = start + req.Limit
+	}
+
+	envelope := client.SearchEnvelope{
+		Users:   users[start:end],
+		Total:   len(users),
+		Offset:  start,
+		Limit:   req.Limit,
+		HasMore: end < len(users),
+	}
+	if ordered && envelope.HasMore && end > start {
+		envelope.NextCursor = newCursor(plan, &users[end-1], false).encode(s.cursorSecret)
+	}
+	if ordered && start > 0 && start < len(users) {
+		envelope.PrevCursor = newCursor(plan, &users[start], true).encode(s.cursorSecret)
+	}
+
+	project(envelope.Users, req.Fields)
+
+	envelope.TookMs = float64(time.Since(began)) / float64(time.Millisecond)
+	usersToJSON, err := json.Marshal(envelope)
+	if err != nil {
+		io.WriteString(w, `{"error": "can't Marshal users to usersToJSON"}`)
+		return
+	}
+	w.Write(usersToJSON)
+}
+
+// Reload - служебная ручка: сразу перечитать данные, если UserStore это умеет
+func (s *Server) Reload(w http.ResponseWriter, r *http.Request) {
+	if !s.authorize(w, r, client.ScopeAdmin) {
+		return
+	}
+
+	store, ok := s.store.(reloader)
+	if !ok {
+		w.WriteHeader(http.StatusNotImplemented)
+		writeError(w, "store can't be reloaded")
+		return
+	}
+	_, err := store.Reload()
+	count := 0
+	if err == nil {
+		count, err = s.store.Count()
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err.Error())
+		return
+	}
+	result, _ := json.Marshal(struct {
+		Users int `json:"users"`
+	}{count})
+	w.Write(result)
+}
+
+// writeStoreError отвечает на ошибку UserStore. Для файлов сохранены прежние ответы:
+// нет файла или его не разобрать - 400, остальное - 500
+func writeStoreError(w http.ResponseWriter, err error) {
+	var pErr *parseError
+	switch {
+	case errors.As(err, &pErr):
+		w.WriteHeader(http.StatusBadRequest) //StatusBadRequest
+		io.WriteString(w, `{"error": "can't unpack result json"}`)
+	case errors.Is(err, os.ErrNotExist):
+		w.WriteHeader(http.StatusBadRequest) //StatusBadRequest
+		io.WriteString(w, `{"error": "no such file or directory"}`)
+	default:
+		log.Printf("user store: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, "can't load users")
+	}
+}
+
+// writeError пишет тело SearchErrorResponse
+func writeError(w io.Writer, message string) {
+	errJSON, _ := json.Marshal(client.SearchErrorResponse{Error: message})
+	w.Write(errJSON)
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"coverage/client"
+)
+
+var (
+	searchServer = New(NewXMLFileStore(testDataset), testTokens, Options{})
+	ts           = httptest.NewServer(searchServer)
+)
+
+const (
+	testDataset = "../dataset.xml"
+	testTokens  = "../testdata/tokens.json"
+	token       = "AccessToken"
+)
+
+type Result struct {
+	Response *client.SearchResponse
+	Error    error
+}
+
+type TestCase struct {
+	Request client.SearchRequest
+	Result  Result
+}
+
+func TestMemoryStoreServer(t *testing.T) {
+	store := NewMemoryStore([]client.User{{Id: 1, Name: "Boyd Wolf"}, {Id: 2, Name: "Hilda Mayer"}})
+	storeTs := httptest.NewServer(New(store, testTokens, Options{}))
+	defer storeTs.Close()
+
+	s := &client.SearchClient{AccessToken: token, URL: storeTs.URL}
+	result, err := s.FindUsers(client.SearchRequest{Limit: 10, Query: "Hilda"})
+	if err != nil || result.Total != 1 || result.Users[0].Id != 2 {
+		t.Errorf("wrong result, expected Hilda from memory store, got %v, %v", result, err)
+	}
+
+	req, _ := http.NewRequest("POST", storeTs.URL+"/admin/reload", nil)
+	req.Header.Set("AccessToken", "AdminToken")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotImplemented {
+		t.Errorf("wrong result, expected %d for store without reload, got %d", http.StatusNotImplemented, resp.StatusCode)
+	}
+}
+
+// sliceStore - хранилище без индекса, ищет через Query.Match
+type sliceStore []client.User
+
+func (s sliceStore) Search(query *Query) ([]client.User, error) {
+	found := []client.User{}
+	for i := range s {
+		if query.Match(&s[i]) {
+			found = append(found, s[i])
+		}
+	}
+	return found, nil
+}
+
+func (s sliceStore) Get(id int) (client.User, bool, error) {
+	for _, user := range s {
+		if user.Id == id {
+			return user, true, nil
+		}
+	}
+	return client.User{}, false, nil
+}
+
+func (s sliceStore) Count() (int, error) {
+	return len(s), nil
+}
+
+func (s sliceStore) Iterate(fn func(client.User) bool) error {
+	for _, user := range s {
+		if !fn(user) {
+			break
+		}
+	}
+	return nil
+}
+
+func TestCustomStoreServer(t *testing.T) {
+	store := sliceStore{{Id: 1, Name: "Boyd Wolf"}, {Id: 2, Name: "Boid Snow"}, {Id: 3, Name: "Hilda Mayer"}}
+	storeTs := httptest.NewServer(New(store, testTokens, Options{}))
+	defer storeTs.Close()
+
+	// найденные без опечаток выше, даже если хранилище о группах не знает
+	s := &client.SearchClient{AccessToken: token, URL: storeTs.URL}
+	result, err := s.FindUsers(client.SearchRequest{Limit: 10, Query: "Boid", Fuzzy: true, Sort: []client.SortKey{{Field: "Id"}}})
+	if err != nil || !reflect.DeepEqual(userIds(result.Users), []int{2, 1}) {
+		t.Errorf("wrong result, expected [2 1], got %v, %v", result, err)
+	}
+}
+
+func TestHugeLimit(t *testing.T) {
+	for _, order := range []string{"order_by=0", "order_by=1&order_field=Id"} {
+		req, _ := http.NewRequest("GET", ts.URL+"?limit=9223372036854775807&offset=1&"+order, nil)
+		req.Header.Set("AccessToken", token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: expected nil, got error: %v", order, err)
+		}
+		envelope := client.SearchEnvelope{}
+		err = json.NewDecoder(resp.Body).Decode(&envelope)
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK || len(envelope.Users) != envelope.Total-1 || envelope.HasMore {
+			t.Errorf("%s: wrong result, expected all users after the first, got %d %d of %d, %v", order, resp.StatusCode, len(envelope.Users), envelope.Total, err)
+		}
+	}
+}
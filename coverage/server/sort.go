@@ -0,0 +1,230 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"coverage/client"
+)
+
+var (
+	errBadOrderField = errors.New("ErrorBadOrderField")
+	errBadOrderBy    = errors.New("have no such sort parameter")
+)
+
+// userFields - номер поля User по имени в нижнем регистре, чтобы сортировать
+// по любому полю без отдельного кода для каждого
+var userFields = map[string]int{}
+
+func init() {
+	t := reflect.TypeOf(client.User{})
+	for i := 0; i < t.NumField(); i++ {
+		userFields[strings.ToLower(t.Field(i).Name)] = i
+	}
+	userFields[strings.ToLower(OrderFieldRelevance)] = userFields["score"]
+}
+
+// sortKeys - ключи сортировки запроса. Если Sort не задан, он собирается из OrderField и OrderBy
+func sortKeys(req client.SearchRequest) ([]client.SortKey, error) {
+	if len(req.Sort) > 0 {
+		for _, key := range req.Sort {
+			if _, ok := userFields[strings.ToLower(key.Field)]; !ok {
+				return nil, fmt.Errorf("unknown sort field %q", key.Field)
+			}
+		}
+		return req.Sort, nil
+	}
+
+	if req.OrderBy == 0 {
+		return nil, nil
+	}
+	if req.OrderBy != 1 && req.OrderBy != -1 {
+		return nil, errBadOrderBy
+	}
+	field := req.OrderField
+	if field == "" {
+		field = "Name"
+	}
+	if _, ok := userFields[strings.ToLower(field)]; !ok {
+		return nil, errBadOrderField
+	}
+	// на сервере 1 всегда означало по возрастанию. Relevance появилась позже и следует
+	// именам констант клиента: OrderByDesc - сначала самые релевантные
+	desc := req.OrderBy == -1
+	if userFields[strings.ToLower(field)] == userFields["score"] {
+		desc = req.OrderBy == client.OrderByDesc
+	}
+	return []client.SortKey{{Field: field, Desc: desc}}, nil
+}
+
+// needsScore - сортируем ли по релевантности, то есть нужно ли считать Score
+func needsScore(keys []client.SortKey) bool {
+	for _, key := range keys {
+		if userFields[strings.ToLower(key.Field)] == userFields["score"] {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSort разбирает параметр sort: "Age desc, Name asc, Id"
+func parseSort(param string) ([]client.SortKey, error) {
+	if param == "" {
+		return nil, nil
+	}
+	var keys []client.SortKey
+	for _, part := range strings.Split(param, ",") {
+		words := strings.Fields(part)
+		if len(words) == 0 || len(words) > 2 {
+			return nil, fmt.Errorf("bad sort key %q", strings.TrimSpace(part))
+		}
+		key := client.SortKey{Field: words[0]}
+		if len(words) == 2 {
+			switch strings.ToLower(words[1]) {
+			case "asc":
+			case "desc":
+				key.Desc = true
+			default:
+				return nil, fmt.Errorf("bad sort direction %q, expected asc or desc", words[1])
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// sortValue достаёт из пользователя значение поля для сравнения.
+// Balance и Registered хранятся строками, сравнивать их надо как деньги и даты
+func sortValue(user *client.User, field int) filterValue {
+	v := reflect.ValueOf(user).Elem().Field(field)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return filterValue{}
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Int:
+		return filterValue{num: float64(v.Int())}
+	case reflect.Float64:
+		return filterValue{num: v.Float()}
+	case reflect.Bool:
+		if v.Bool() {
+			return filterValue{num: 1}
+		}
+		return filterValue{}
+	}
+
+	s := v.String()
+	name := reflect.TypeOf(client.User{}).Field(field).Name
+	if f, ok := filterFields[strings.ToLower(name[:1])+name[1:]]; ok && (f.kind == kindMoney || f.kind == kindTime) {
+		if parsed, err := parseValue(f.kind, s); err == nil {
+			return parsed
+		}
+	}
+	return filterValue{str: s}
+}
+
+// sortPlan - полный порядок пользователей: при Fuzzy сначала найденные без опечаток,
+// потом ключи сортировки, и в конце Id, чтобы порядок был однозначным и курсоры стабильными
+type sortPlan struct {
+	keys   []client.SortKey
+	fields []int
+	desc   []bool
+	exact  map[int]bool // nil, если запрос без Fuzzy
+}
+
+func newSortPlan(keys []client.SortKey, exact map[int]bool) *sortPlan {
+	p := &sortPlan{keys: keys, exact: exact}
+	for _, key := range keys {
+		p.fields = append(p.fields, userFields[strings.ToLower(key.Field)])
+		p.desc = append(p.desc, key.Desc)
+	}
+	p.fields = append(p.fields, userFields["id"])
+	p.desc = append(p.desc, false)
+	return p
+}
+
+// spec - описание порядка, курсор годится только для того же порядка
+func (p *sortPlan) spec() string {
+	keys := make([]string, 0, len(p.keys))
+	for _, key := range p.keys {
+		keys = append(keys, strings.ToLower(key.String()))
+	}
+	spec := strings.Join(keys, ",")
+	if p.exact != nil {
+		spec = "fuzzy;" + spec
+	}
+	return spec
+}
+
+// values - значения, по которым сравниваются пользователи
+func (p *sortPlan) values(user *client.User) []filterValue {
+	values := make([]filterValue, 0, len(p.fields)+1)
+	if p.exact != nil {
+		group := filterValue{}
+		if !p.exact[user.Id] {
+			group.num = 1
+		}
+		values = append(values, group)
+	}
+	for _, field := range p.fields {
+		values = append(values, sortValue(user, field))
+	}
+	return values
+}
+
+// compare сравнивает значения, полученные из values: -1, 0 или 1
+func (p *sortPlan) compare(a, b []filterValue) int {
+	offset := len(a) - len(p.desc) // группа fuzzy, всегда по возрастанию
+	for k := range a {
+		if a[k] == b[k] {
+			continue
+		}
+		less := a[k].num < b[k].num || a[k].num == b[k].num && a[k].str < b[k].str
+		if k >= offset && p.desc[k-offset] {
+			less = !less
+		}
+		if less {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+// usersByPlan сортирует пользователей, значения достаются один раз
+type usersByPlan struct {
+	plan   *sortPlan
+	users  []client.User
+	values [][]filterValue
+}
+
+func (s usersByPlan) Len() int { return len(s.users) }
+
+func (s usersByPlan) Swap(i, j int) {
+	s.users[i], s.users[j] = s.users[j], s.users[i]
+	s.values[i], s.values[j] = s.values[j], s.values[i]
+}
+
+func (s usersByPlan) Less(i, j int) bool {
+	return s.plan.compare(s.values[i], s.values[j]) < 0
+}
+
+func (p *sortPlan) sort(users []client.User) {
+	values := make([][]filterValue, len(users))
+	for i := range users {
+		values[i] = p.values(&users[i])
+	}
+	sort.Sort(usersByPlan{p, users, values})
+}
+
+// search - номер первого пользователя, который в этом порядке не меньше values
+func (p *sortPlan) search(users []client.User, values []filterValue) int {
+	return sort.Search(len(users), func(i int) bool {
+		return p.compare(p.values(&users[i]), values) >= 0
+	})
+}
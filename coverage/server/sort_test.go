@@ -0,0 +1,97 @@
+package server
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"coverage/client"
+)
+
+type TestCaseSort struct {
+	sort   []client.SortKey
+	result []int
+}
+
+func TestSortKeys(t *testing.T) {
+	s := &client.SearchClient{
+		AccessToken: token,
+		URL:         ts.URL,
+	}
+	tests := []TestCaseSort{
+		{
+			sort:   []client.SortKey{{Field: "Age", Desc: true}, {Field: "Name", Desc: false}, {Field: "Id", Desc: false}},
+			result: []int{32, 13, 6, 26, 31, 12, 17, 9},
+		},
+		{
+			sort:   []client.SortKey{{Field: "balance", Desc: false}},
+			result: []int{2, 13, 10, 15, 34},
+		},
+		{
+			sort:   []client.SortKey{{Field: "Registered", Desc: true}},
+			result: []int{8, 23, 0, 32, 1},
+		},
+		{
+			sort:   []client.SortKey{{Field: "IsActive", Desc: true}, {Field: "EyeColor", Desc: false}, {Field: "Id", Desc: false}},
+			result: []int{4, 11, 13, 20, 25, 26},
+		},
+	}
+
+	for caseNum, testItem := range tests {
+		result, err := s.FindUsers(client.SearchRequest{
+			Limit:   len(testItem.result),
+			OrderBy: 2, // при заданном Sort не учитывается
+			Sort:    testItem.sort,
+		})
+		if err != nil {
+			t.Errorf("[%d] expected nil, got error: %v", caseNum, err)
+			continue
+		}
+		var ids []int
+		for _, user := range result.Users {
+			ids = append(ids, user.Id)
+		}
+		if !reflect.DeepEqual(testItem.result, ids) {
+			t.Errorf("[%d] wrong result, expected %v, got %v", caseNum, testItem.result, ids)
+		}
+	}
+
+	_, err := s.FindUsers(client.SearchRequest{Sort: []client.SortKey{{Field: "Password", Desc: false}}})
+	if err == nil || !strings.Contains(err.Error(), `unknown sort field "Password"`) {
+		t.Errorf("wrong result, expected unknown sort field error, got %v", err)
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	keys, err := parseSort("Age desc, Name asc,Id")
+	expected := []client.SortKey{{Field: "Age", Desc: true}, {Field: "Name", Desc: false}, {Field: "Id", Desc: false}}
+	if err != nil || !reflect.DeepEqual(expected, keys) {
+		t.Errorf("wrong result, expected %v, got %v, %v", expected, keys, err)
+	}
+
+	for caseNum, param := range []string{"Age down", "Age,,Name", "Age desc Name"} {
+		if _, err := parseSort(param); err == nil {
+			t.Errorf("[%d] %s: expected error, got nil", caseNum, param)
+		}
+	}
+}
+
+func TestOrderAsIs(t *testing.T) {
+	store := NewMemoryStore([]client.User{{Id: 3, Name: "Boyd Wolf"}, {Id: 1, Name: "Hilda Mayer"}, {Id: 2, Name: "Brooks Aguilar"}})
+	storeTs := httptest.NewServer(New(store, testTokens, Options{}))
+	defer storeTs.Close()
+
+	s := &client.SearchClient{AccessToken: token, URL: storeTs.URL}
+	result, err := s.FindUsers(client.SearchRequest{Limit: 2, OrderBy: client.OrderByAsIs})
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	if ids := userIds(result.Users); !reflect.DeepEqual(ids, []int{3, 1}) || !result.NextPage {
+		t.Errorf("wrong result, expected [3 1] in store order, got %v", ids)
+	}
+	// курсор дал бы следующую страницу в другом порядке, так что листаем по Offset
+	if result.NextCursor != "" {
+		t.Errorf("wrong result, expected no NextCursor without sort keys, got %q", result.NextCursor)
+	}
+}
@@ -0,0 +1,127 @@
+package server
+
+import "coverage/client"
+
+// Store - UserStore в памяти: пользователи и индекс по ним. Его собирают загрузчики файлов,
+// а в тестах удобно собрать его из готовых пользователей через NewMemoryStore
+type Store struct {
+	users []client.User
+	index *Index
+	byID  map[int]int // Id -> номер в users
+}
+
+// NewMemoryStore индексирует users. Срез дальше принадлежит Store, менять его нельзя
+func NewMemoryStore(users []client.User) *Store {
+	byID := make(map[int]int, len(users))
+	for i, user := range users {
+		byID[user.Id] = i
+	}
+	return &Store{users: users, index: NewIndex(users), byID: byID}
+}
+
+// parseError - файл прочитан, но разобрать его не удалось
+type parseError struct {
+	err error
+}
+
+func (e *parseError) Error() string {
+	return "can't unpack dataset: " + e.err.Error()
+}
+
+func (e *parseError) Unwrap() error {
+	return e.err
+}
+
+func rowToUser(r row) client.User {
+	isActive := r.IsActive
+	return client.User{
+		Id:            r.ID,
+		Name:          r.FirstName + " " + r.LastName,
+		Age:           r.Age,
+		About:         r.About,
+		Gender:        r.Gender,
+		GUID:          r.GUID,
+		IsActive:      &isActive,
+		Balance:       r.Balance,
+		Picture:       r.Picture,
+		EyeColor:      r.EyeColor,
+		Company:       r.Company,
+		Email:         r.Email,
+		Phone:         r.Phone,
+		Address:       r.Address,
+		Registered:    r.Registered,
+		FavoriteFruit: r.FavoriteFruit,
+	}
+}
+
+// Find возвращает копию подходящих под запрос пользователей в порядке файла,
+// так что её можно сортировать, не трогая сам Store.
+// При query.Fuzzy сначала идут точные совпадения, потом найденные с опечатками
+func (s *Store) Find(query *Query) []client.User {
+	return s.search(query, false)
+}
+
+// Search - UserStore.Search, Store не ошибается
+func (s *Store) Search(query *Query) ([]client.User, error) {
+	return s.Find(query), nil
+}
+
+// search - общая часть Find и Rank
+func (s *Store) search(query *Query, rank bool) []client.User {
+	var users []client.User
+	groups := [][]int{query.docs(s, false)}
+	if query.Fuzzy {
+		groups = append(groups, difference(query.docs(s, true), groups[0]))
+	}
+
+	terms := query.Terms()
+	for _, docs := range groups {
+		var scores []float64
+		if rank {
+			scores = s.index.Scores(terms, docs)
+			if query.Fuzzy {
+				s.index.addFuzzyScores(terms, docs, scores)
+			}
+		}
+		for i, doc := range docs {
+			user := s.users[doc]
+			if rank {
+				user.Score = scores[i]
+			}
+			users = append(users, user)
+		}
+	}
+	if users == nil {
+		users = []client.User{}
+	}
+	return users
+}
+
+// Len - количество пользователей в хранилище
+func (s *Store) Len() int {
+	return len(s.users)
+}
+
+// Get - пользователь по Id
+func (s *Store) Get(id int) (client.User, bool, error) {
+	i, ok := s.byID[id]
+	if !ok {
+		return client.User{}, false, nil
+	}
+	return s.users[i], true, nil
+}
+
+// Count - UserStore.Count, то же, что Len
+func (s *Store) Count() (int, error) {
+	return len(s.users), nil
+}
+
+// Iterate перебирает пользователей в порядке файла
+func (s *Store) Iterate(fn func(client.User) bool) error {
+	for _, user := range s.users {
+		if !fn(user) {
+			break
+		}
+	}
+	return nil
+}
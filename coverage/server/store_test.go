@@ -0,0 +1,165 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"coverage/client"
+)
+
+func TestStoreFindCopy(t *testing.T) {
+	store, err := NewStore(testDataset)
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+
+	all := &Query{}
+	users := store.Find(all)
+	if len(users) != store.Len() {
+		t.Errorf("wrong result, expected %v, got %v", store.Len(), len(users))
+	}
+	users[0].Name = "changed"
+	if store.Find(all)[0].Name == "changed" {
+		t.Errorf("Find must return a copy of users")
+	}
+}
+
+func TestStoreErrors(t *testing.T) {
+	if _, err := NewStore(""); err == nil {
+		t.Errorf("expected error for missing file, got nil")
+	}
+	_, err := NewStore("../coverfile.html")
+	if _, ok := err.(*parseError); !ok {
+		t.Errorf("expected parseError, got %#v", err)
+	}
+}
+
+func TestStoreGetIterate(t *testing.T) {
+	store := NewMemoryStore([]client.User{{Id: 7, Name: "Boyd Wolf"}, {Id: 3, Name: "Hilda Mayer"}, {Id: 5, Name: "Annie Osborn"}})
+
+	if user, ok, err := store.Get(3); !ok || err != nil || user.Name != "Hilda Mayer" {
+		t.Errorf("wrong result, expected Hilda Mayer, got %v, %v, %v", user, ok, err)
+	}
+	if _, ok, _ := store.Get(4); ok {
+		t.Errorf("wrong result, expected no user with Id 4")
+	}
+	if count, err := store.Count(); count != 3 || err != nil {
+		t.Errorf("wrong result, expected 3, got %v, %v", count, err)
+	}
+
+	var ids []int
+	store.Iterate(func(user client.User) bool {
+		ids = append(ids, user.Id)
+		return len(ids) < 2
+	})
+	if !reflect.DeepEqual(ids, []int{7, 3}) {
+		t.Errorf("wrong result, expected to stop after 2 users, got %v", ids)
+	}
+}
+
+func TestJSONLinesStore(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "dataset.jsonl")
+	writeDataset(t, fileName, `{"id": 1, "first_name": "Boyd", "last_name": "Wolf", "age": 22}
+
+{"id": 2, "first_name": "Hilda", "last_name": "Mayer", "eyeColor": "blue"}
+`, time.Now())
+
+	store, err := NewJSONLinesStore(fileName)
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	user, ok, _ := store.Get(2)
+	if store.Len() != 2 || !ok || user.Name != "Hilda Mayer" || user.EyeColor != "blue" {
+		t.Errorf("wrong result, got %d users, %+v", store.Len(), user)
+	}
+
+	writeDataset(t, fileName, "{\"id\": 1}\n{\"id\": \"two\"}\n", time.Now())
+	_, err = NewJSONLinesStore(fileName)
+	if _, ok := err.(*parseError); !ok || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected parseError on line 2, got %v", err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "dataset.jsonl")
+	writeDataset(t, fileName, `{"id": 1, "first_name": "Boyd"}`, time.Now())
+
+	var store UserStore = NewJSONLinesFileStore(fileName)
+	if count, err := store.Count(); count != 1 || err != nil {
+		t.Errorf("wrong result, expected 1, got %v, %v", count, err)
+	}
+
+	missing := NewXMLFileStore(filepath.Join(t.TempDir(), "missing.xml"))
+	if _, err := missing.Count(); !os.IsNotExist(err) {
+		t.Errorf("expected not exist error, got %v", err)
+	}
+
+	// у каждого FileStore свой загрузчик, даже если файл тот же
+	if _, err := NewXMLFileStore(fileName).Count(); err == nil {
+		t.Errorf("expected XML loader to fail on JSON lines, got nil")
+	}
+}
+
+func TestFileStoreClose(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "dataset.xml")
+	now := time.Now()
+	writeDataset(t, fileName, datasetTwoRows, now)
+
+	store := NewFileStore(fileName, NewStore, FileOptions{ReloadInterval: time.Millisecond})
+	if count, err := store.Count(); count != 2 || err != nil {
+		t.Fatalf("wrong result, expected 2, got %v, %v", count, err)
+	}
+	store.Close()
+
+	// после Close файл больше сам не перечитывается, но Reload работает
+	writeDataset(t, fileName, `<root><row><id>1</id></row></root>`, now.Add(time.Second))
+	time.Sleep(20 * time.Millisecond)
+	if count, _ := store.Count(); count != 2 {
+		t.Errorf("wrong result, expected no reload after Close, got %d users", count)
+	}
+	if changed, err := store.Reload(); !changed || err != nil {
+		t.Errorf("wrong result, expected reload, got %v, %v", changed, err)
+	}
+	if count, _ := store.Count(); count != 1 {
+		t.Errorf("wrong result, expected 1 user after Reload, got %d", count)
+	}
+}
+
+// BenchmarkFindParsePerRequest - как было раньше: файл читается и разбирается на каждый запрос
+func BenchmarkFindParsePerRequest(b *testing.B) {
+	query, _ := ParseQuery("B")
+	for i := 0; i < b.N; i++ {
+		store, err := NewStore(testDataset)
+		if err != nil {
+			b.Fatal(err)
+		}
+		store.Find(query)
+	}
+}
+
+// BenchmarkFindStore - поиск по уже загруженному Store
+func BenchmarkFindStore(b *testing.B) {
+	store, err := NewStore(testDataset)
+	if err != nil {
+		b.Fatal(err)
+	}
+	query, _ := ParseQuery("B")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		store.Find(query)
+	}
+}
+
+func BenchmarkSearchServer(b *testing.B) {
+	r := httptest.NewRequest(http.MethodGet, "/?limit=10&offset=0&query=B&order_field=Name&order_by=1", nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		searchServer.ServeHTTP(httptest.NewRecorder(), r)
+	}
+}
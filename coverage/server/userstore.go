@@ -0,0 +1,169 @@
+package server
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"coverage/client"
+)
+
+// UserStore - хранилище пользователей. Server работает только через него,
+// так что своё хранилище можно подключить, не трогая HTTP-часть.
+// Сортировка, фильтры и разделение на найденных с опечатками и без - дело Server
+type UserStore interface {
+	// Search - пользователи, подходящие под query, в порядке хранилища. Хранилищу
+	// без своего индекса достаточно перебрать всех и оставить тех, для кого query.Match.
+	// Слайс переходит к Server: он фильтрует, сортирует и обрезает поля прямо в нём,
+	// так что хранилище должно каждый раз отдавать новый, а не свой собственный
+	Search(query *Query) ([]client.User, error)
+	// Get - пользователь по Id; false, если такого нет
+	Get(id int) (client.User, bool, error)
+	// Count - сколько всего пользователей
+	Count() (int, error)
+	// Iterate вызывает fn для всех пользователей по порядку, пока fn не вернёт false
+	Iterate(fn func(client.User) bool) error
+}
+
+// ranker - UserStore, который умеет считать релевантность: Rank - то же, что Search,
+// но с заполненным User.Score, и слайс так же переходит к Server.
+// У остальных хранилищ при сортировке по Relevance Score нулевой
+type ranker interface {
+	Rank(query *Query) ([]client.User, error)
+}
+
+// Loader читает файл с пользователями целиком в Store, например NewStore или NewJSONLinesStore
+type Loader func(fileName string) (*Store, error)
+
+// FileOptions - настройки FileStore
+type FileOptions struct {
+	// как часто проверять, не изменился ли файл; 0 - не проверять, перечитывать только через Reload
+	ReloadInterval time.Duration
+}
+
+// FileStore - UserStore поверх файла. Файл загружается при первом обращении,
+// дальше за ним следит Dataset и подменяет данные, когда файл меняется
+type FileStore struct {
+	fileName string
+	load     Loader
+	opts     FileOptions
+
+	mu      sync.Mutex // не даёт загрузить файл дважды
+	dataset *Dataset
+	stop    func() // останавливает Watch
+	closed  bool
+}
+
+// NewFileStore - файл fileName, который читается через load
+func NewFileStore(fileName string, load Loader, opts FileOptions) *FileStore {
+	return &FileStore{fileName: fileName, load: load, opts: opts}
+}
+
+// NewXMLFileStore - файл в формате dataset.xml, см. NewStore. Проверяется раз в DefaultReloadInterval
+func NewXMLFileStore(fileName string) *FileStore {
+	return NewFileStore(fileName, NewStore, FileOptions{ReloadInterval: DefaultReloadInterval})
+}
+
+// NewJSONLinesFileStore - файл, где каждая строка - объект с полями row, см. NewJSONLinesStore.
+// Проверяется раз в DefaultReloadInterval
+func NewJSONLinesFileStore(fileName string) *FileStore {
+	return NewFileStore(fileName, NewJSONLinesStore, FileOptions{ReloadInterval: DefaultReloadInterval})
+}
+
+// open отдаёт Dataset файла. Файл загружается при первом обращении, дальше за ним следит Watch
+func (f *FileStore) open() (*Dataset, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.dataset != nil {
+		return f.dataset, nil
+	}
+	d, err := OpenDataset(f.fileName, f.load)
+	if err != nil {
+		return nil, err
+	}
+	if f.opts.ReloadInterval > 0 && !f.closed {
+		f.stop = d.Watch(f.opts.ReloadInterval, func(err error) {
+			log.Printf("reload %s: %v, keep serving previous version", f.fileName, err)
+		})
+	}
+	f.dataset = d
+	return d, nil
+}
+
+// Close перестаёт следить за файлом. Загруженные данные остаются доступны,
+// обновить их можно только через Reload
+func (f *FileStore) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	if f.stop != nil {
+		f.stop()
+		f.stop = nil
+	}
+	return nil
+}
+
+// current - последняя загруженная версия файла
+func (f *FileStore) current() (*Store, error) {
+	d, err := f.open()
+	if err != nil {
+		return nil, err
+	}
+	return d.Store(), nil
+}
+
+// Search ищет в текущей версии файла
+func (f *FileStore) Search(query *Query) ([]client.User, error) {
+	store, err := f.current()
+	if err != nil {
+		return nil, err
+	}
+	return store.Search(query)
+}
+
+// Rank ищет в текущей версии файла и считает релевантность
+func (f *FileStore) Rank(query *Query) ([]client.User, error) {
+	store, err := f.current()
+	if err != nil {
+		return nil, err
+	}
+	return store.Rank(query)
+}
+
+// Get - пользователь по Id из текущей версии файла
+func (f *FileStore) Get(id int) (client.User, bool, error) {
+	store, err := f.current()
+	if err != nil {
+		return client.User{}, false, err
+	}
+	return store.Get(id)
+}
+
+// Count - сколько пользователей в текущей версии файла
+func (f *FileStore) Count() (int, error) {
+	store, err := f.current()
+	if err != nil {
+		return 0, err
+	}
+	return store.Count()
+}
+
+// Iterate перебирает пользователей текущей версии файла
+func (f *FileStore) Iterate(fn func(client.User) bool) error {
+	store, err := f.current()
+	if err != nil {
+		return err
+	}
+	return store.Iterate(fn)
+}
+
+// Reload сразу перечитывает файл, не дожидаясь FileOptions.ReloadInterval
+func (f *FileStore) Reload() (bool, error) {
+	d, err := f.open()
+	if err != nil {
+		return false, err
+	}
+	return d.Reload()
+}
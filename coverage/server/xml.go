@@ -0,0 +1,125 @@
+package server
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+
+	"coverage/client"
+)
+
+// LoadProgress - сколько файла с пользователями уже загружено
+type LoadProgress struct {
+	// разобрано записей
+	Rows int
+	// прочитано байт файла
+	Bytes int64
+	// размер файла; 0 - неизвестен
+	Total int64
+}
+
+// progressEvery - раз во сколько записей NewXMLStore сообщает о прогрессе
+var progressEvery = 10000
+
+// NewStore читает XML-файл <root><row>...</row></root> и сразу переводит строки row в User
+func NewStore(fileName string) (*Store, error) {
+	return NewXMLStore(fileName, nil)
+}
+
+// NewXMLStore - NewStore с отчётом о прогрессе. Файл разбирается потоком по одной <row>,
+// так что в памяти не бывает ни файла целиком, ни дерева всех row - только уже готовые User.
+// onProgress, если задан, вызывается каждые progressEvery записей и в конце
+func NewXMLStore(fileName string, onProgress func(LoadProgress)) (*Store, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var size int64
+	if info, err := file.Stat(); err == nil {
+		size = info.Size()
+	}
+	users, err := decodeXML(file, size, onProgress)
+	if err != nil {
+		return nil, err
+	}
+	return NewMemoryStore(users), nil
+}
+
+// xmlRow - row, в которой видно, был ли <id> вообще: без него ID остался бы нулём,
+// а 0 - настоящий Id первого пользователя dataset.xml
+type xmlRow struct {
+	row
+	ID *int `xml:"id"`
+}
+
+// decodeXML разбирает <row> - прямых потомков корневого элемента, как раньше root.RowMas.
+// Остальные элементы пропускаются. Как и в других форматах, запись без id или с повторным id - ошибка
+func decodeXML(r io.Reader, size int64, onProgress func(LoadProgress)) ([]client.User, error) {
+	dec := xml.NewDecoder(r)
+	set := newRowSet()
+	report := func() {
+		if onProgress != nil {
+			onProgress(LoadProgress{Rows: len(set.users), Bytes: dec.InputOffset(), Total: size})
+		}
+	}
+
+	depth, sawRoot := 0, false
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, &parseError{err}
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth == 1 && t.Name.Local == "row" {
+				line, _ := dec.InputPos()
+				r := xmlRow{}
+				// DecodeElement дочитывает row до закрывающего тега, глубина не меняется
+				if err := dec.DecodeElement(&r, &t); err != nil {
+					return nil, recordError(line, set.next(), "%v", err)
+				}
+				if r.ID == nil {
+					return nil, recordError(line, set.next(), "no %s", requiredColumn)
+				}
+				r.row.ID = *r.ID
+				if err := set.add(r.row, line); err != nil {
+					return nil, err
+				}
+				if len(set.users)%progressEvery == 0 {
+					report()
+				}
+				continue
+			}
+			depth++
+			sawRoot = true
+		case xml.EndElement:
+			depth--
+		}
+	}
+	if !sawRoot {
+		return nil, &parseError{io.EOF}
+	}
+	report()
+	return set.users, nil
+}
+
+// LoadDatasetProgress - LoadDataset, который сообщает о прогрессе загрузки XML, см. NewXMLStore.
+// Остальные форматы грузятся как обычно
+func LoadDatasetProgress(onProgress func(LoadProgress)) Loader {
+	return func(fileName string) (*Store, error) {
+		format, err := DetectFormat(fileName)
+		if err != nil {
+			return nil, err
+		}
+		if format == FormatXML {
+			return NewXMLStore(fileName, onProgress)
+		}
+		return formatLoaders[format](fileName)
+	}
+}
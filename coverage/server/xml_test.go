@@ -0,0 +1,231 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"runtime/metrics"
+	"strings"
+	"testing"
+	"time"
+
+	"coverage/client"
+)
+
+// writeXMLDataset пишет dataset.xml из n синтетических пользователей
+func writeXMLDataset(tb testing.TB, fileName string, n int) {
+	file, err := os.Create(fileName)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	w.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\" ?>\n<root>\n")
+	for _, user := range syntheticUsers(n) {
+		name := strings.SplitN(user.Name, " ", 2)
+		fmt.Fprintf(w, "  <row>\n    <id>%d</id>\n    <age>%d</age>\n    <first_name>%s</first_name>\n    <last_name>%s</last_name>\n    <about>",
+			user.Id, 20+user.Id%40, name[0], name[1])
+		xml.EscapeText(w, []byte(user.About))
+		w.WriteString("</about>\n  </row>\n")
+	}
+	w.WriteString("</root>\n")
+	if err := w.Flush(); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// unmarshalXML - прежний загрузчик: файл целиком в память и один xml.Unmarshal
+// в дерево всех row. Нужен для сравнения с decodeXML
+func unmarshalXML(fileName string) ([]client.User, error) {
+	xmlData, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	xmlUsers := struct {
+		RowMas []row `xml:"row"`
+	}{}
+	if err := xml.Unmarshal(xmlData, &xmlUsers); err != nil {
+		return nil, &parseError{err}
+	}
+	users := make([]client.User, 0, len(xmlUsers.RowMas))
+	for _, row := range xmlUsers.RowMas {
+		users = append(users, rowToUser(row))
+	}
+	return users, nil
+}
+
+// streamXML - то же через decodeXML
+func streamXML(fileName string) ([]client.User, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return decodeXML(file, 0, nil)
+}
+
+// parseStore разбирает XML, уже прочитанный в память
+func parseStore(xmlData []byte) (*Store, error) {
+	users, err := decodeXML(bytes.NewReader(xmlData), int64(len(xmlData)), nil)
+	if err != nil {
+		return nil, err
+	}
+	return NewMemoryStore(users), nil
+}
+
+func TestXMLStoreMatchesUnmarshal(t *testing.T) {
+	expected, err := unmarshalXML(testDataset)
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	store, err := NewStore(testDataset)
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+	if !reflect.DeepEqual(expected, store.users) {
+		t.Errorf("wrong result, streaming loader differs from xml.Unmarshal")
+	}
+}
+
+func TestXMLStoreProgress(t *testing.T) {
+	defer func(every int) { progressEvery = every }(progressEvery)
+	progressEvery = 10
+	fileName := filepath.Join(t.TempDir(), "dataset.xml")
+	writeXMLDataset(t, fileName, 25)
+
+	var reports []LoadProgress
+	store, err := NewXMLStore(fileName, func(p LoadProgress) {
+		reports = append(reports, p)
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got error: %v", err)
+	}
+
+	info, _ := os.Stat(fileName)
+	if len(reports) != 3 || reports[0].Rows != progressEvery || reports[1].Rows != 2*progressEvery {
+		t.Fatalf("wrong result, expected reports every %d rows, got %+v", progressEvery, reports)
+	}
+	last := reports[2]
+	if last.Rows != store.Len() || last.Bytes != info.Size() || last.Total != info.Size() {
+		t.Errorf("wrong result, expected final report %d rows, %d bytes, got %+v", store.Len(), info.Size(), last)
+	}
+	if reports[0].Bytes <= 0 || reports[0].Bytes >= reports[1].Bytes {
+		t.Errorf("wrong result, expected growing Bytes, got %+v", reports)
+	}
+}
+
+func TestXMLStoreStructure(t *testing.T) {
+	// как и раньше с root.RowMas, берутся только row - прямые потомки корня
+	store, err := parseStore([]byte(`<root>
+<meta><row><id>9</id></row></meta>
+<row><id>1</id><first_name>Boyd</first_name></row>
+<row><id>2</id><first_name>Hilda</first_name></row>
+</root>`))
+	if err != nil || store.Len() != 2 {
+		t.Fatalf("wrong result, expected 2 users, got %v, %v", store, err)
+	}
+	if _, ok, _ := store.Get(9); ok {
+		t.Errorf("nested row must be skipped")
+	}
+
+	tests := []struct {
+		data   string
+		line   int
+		record int
+	}{
+		{"<root>\n<row><id>1</id></row>\n<row><id>2</id><age>old</age></row>\n</root>", 3, 2},
+		{"<root>\n<row><id>1</id>\n</root>", 2, 1},
+		{"<root>\n<row><id>1</id></row>\n<row>\n<first_name>Boyd</first_name></row>\n</root>", 3, 2},
+		{"<root>\n<row><id>1</id></row>\n<row><id>0</id></row>\n<row><id>1</id></row>\n</root>", 4, 3},
+	}
+	for caseNum, testItem := range tests {
+		_, err := parseStore([]byte(testItem.data))
+		var recordErr *RecordError
+		if !errors.As(err, &recordErr) || recordErr.Line != testItem.line || recordErr.Record != testItem.record {
+			t.Errorf("[%d] wrong result, expected line %d, record %d, got %v", caseNum, testItem.line, testItem.record, err)
+		}
+	}
+
+	for caseNum, data := range []string{"", "broken", "<root><row>"} {
+		if _, err := parseStore([]byte(data)); err == nil {
+			t.Errorf("[%d] expected error, got nil", caseNum)
+		}
+	}
+}
+
+// benchRows - сколько пользователей в файле для бенчмарков загрузки, около 25 МБ
+const benchRows = 50000
+
+// peakHeap выполняет load и возвращает наибольший размер кучи за это время
+func peakHeap(load func()) uint64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	read := func() uint64 {
+		metrics.Read(sample)
+		return sample[0].Value.Uint64()
+	}
+
+	runtime.GC()
+	peak := read()
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if heap := read(); heap > peak {
+					peak = heap
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	load()
+	close(done)
+	<-stopped
+	return peak
+}
+
+func benchmarkLoadXML(b *testing.B, load func(fileName string) ([]client.User, error)) {
+	fileName := filepath.Join(b.TempDir(), "dataset.xml")
+	writeXMLDataset(b, fileName, benchRows)
+	info, _ := os.Stat(fileName)
+
+	b.ReportAllocs()
+	b.SetBytes(info.Size())
+	b.ResetTimer()
+	var peak uint64
+	for i := 0; i < b.N; i++ {
+		heap := peakHeap(func() {
+			if _, err := load(fileName); err != nil {
+				b.Fatal(err)
+			}
+		})
+		if heap > peak {
+			peak = heap
+		}
+	}
+	b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MB")
+}
+
+// BenchmarkLoadXMLUnmarshal - прежний способ: весь файл и все row в памяти одновременно.
+// Индекс в обоих бенчмарках не строится, он одинаковый и заслонил бы разницу
+func BenchmarkLoadXMLUnmarshal(b *testing.B) {
+	benchmarkLoadXML(b, unmarshalXML)
+}
+
+// BenchmarkLoadXMLStream - decodeXML: по одной row за раз
+func BenchmarkLoadXMLStream(b *testing.B) {
+	benchmarkLoadXML(b, streamXML)
+}